@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+// runCheck checks the URLs listed within a file or stdin without crawling
+// them.
+func runCheck(args []string) {
+	var opts options
+
+	fs := flag.NewFlagSet("spidy check", flag.ExitOnError)
+	opts.register(fs)
+
+	fs.Usage = func() {
+		fmt.Print(`
+Spidy Check - Checks a list of URLs without crawling them.
+
+Each line holds a URL, optionally followed by a tab or spaces and the source
+the URL came from, which is kept in the report. Blank lines and lines starting
+with # are skipped.
+
+Flags:
+
+ The same flags as spidy apply, excluding those for the crawl such as -scope.
+
+Usage:
+
+	// To check the URLs listed within a file
+	spidy check links.txt
+
+	// To check URLs piped from another tool, reporting as JSON
+	cat links.txt | spidy check -format json -output report.json
+
+`)
+	}
+
+	fs.Parse(args)
+	opts.env()
+
+	var in io.Reader = os.Stdin
+
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		file, err := os.Open(fs.Arg(0))
+		if err != nil {
+			events.ErrorEvent(context, "check", err, "Configuration Error : Initialization Failed")
+			os.Exit(1)
+		}
+
+		defer file.Close()
+		in = file
+
+	default:
+		events.ErrorEvent(context, "check", errors.New("Too many arguments"), "Configuration Error : Initialization Failed")
+		os.Exit(1)
+	}
+
+	links, err := spidy.ReadLinks(in)
+	if err != nil {
+		events.ErrorEvent(context, "check", err, "Configuration Error : Reading Links Failed")
+		os.Exit(1)
+	}
+
+	conf, err := opts.config()
+	if err != nil {
+		opts.close()
+		events.ErrorEvent(context, "check", err, "Configuration Error : Initialization Failed")
+		os.Exit(1)
+	}
+
+	report, err := spidy.Check(context, &conf, links)
+
+	if err := opts.close(); err != nil {
+		events.ErrorEvent(context, "check", err, "Closing Stores")
+	}
+
+	if err != nil {
+		events.ErrorEvent(context, "check", err, "Completed")
+		os.Exit(1)
+	}
+
+	if err := opts.writeReport(report); err != nil {
+		events.ErrorEvent(context, "check", err, "Completed")
+		os.Exit(1)
+	}
+
+	if report.Failed() {
+		os.Exit(-1)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+// runDiff compares the reports of two runs, exiting with a failure only if
+// the newer report holds new errors.
+func runDiff(args []string) {
+	var opts options
+
+	fs := flag.NewFlagSet("spidy diff", flag.ExitOnError)
+	fs.StringVar(&opts.format, "format", spidy.FormatText, "Format of the diff: text, json or csv")
+	fs.StringVar(&opts.output, "output", "", "File to write the diff to, defaults to stdout")
+
+	fs.Usage = func() {
+		fmt.Print(`
+Spidy Diff - Compares the reports of two runs.
+
+Findings are classified as newly broken, fixed or still broken, matching them
+by their canonical URL and referrer. Reports of any format can be compared:
+reports written as text, the default format of spidy, are told apart by their
+content, those whose file ends in .csv are read as CSV and others as JSON.
+Reports read from text or CSV hold only their findings, which are all a diff
+compares.
+
+Flags:
+
+ -format "Format of the diff: text, json or csv, defaults to text"
+ -output "File to write the diff to, defaults to stdout"
+
+Usage:
+
+	// To compare last night's report with tonight's
+	spidy -url http://example.com -output new.txt
+	spidy diff old.txt new.txt
+
+`)
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		events.ErrorEvent(context, "diff", errors.New("Expected an old and a new report"), "Configuration Error : Initialization Failed")
+		os.Exit(1)
+	}
+
+	older, err := readReport(fs.Arg(0))
+	if err != nil {
+		events.ErrorEvent(context, "diff", err, "Configuration Error : Reading Report Failed")
+		os.Exit(1)
+	}
+
+	newer, err := readReport(fs.Arg(1))
+	if err != nil {
+		events.ErrorEvent(context, "diff", err, "Configuration Error : Reading Report Failed")
+		os.Exit(1)
+	}
+
+	diff := spidy.Diff(older, newer)
+
+	if err := opts.write(func(w io.Writer) error {
+		return spidy.WriteDiff(w, diff, opts.format)
+	}); err != nil {
+		events.ErrorEvent(context, "diff", err, "Completed")
+		os.Exit(1)
+	}
+
+	// Findings which were already broken don't fail the diff.
+	if diff.Regressed() {
+		os.Exit(-1)
+	}
+}
+
+// readReport reads the report within the giving file, as text if it was
+// written as text, as CSV if the file ends in .csv else as JSON.
+func readReport(file string) (*spidy.Report, error) {
+	fd, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	defer fd.Close()
+
+	format := spidy.FormatJSON
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		format = spidy.FormatCSV
+	case ".txt", ".text":
+		format = spidy.FormatText
+	}
+
+	report, err := spidy.ReadReport(fd, format)
+	if err != nil {
+		return nil, fmt.Errorf("Report[%s] : %s", file, err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,626 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/kit/cfg"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+// formFields collects repeated name=value flags into form values.
+type formFields url.Values
+
+// String implements the flag.Value interface.
+func (f formFields) String() string {
+	return url.Values(f).Encode()
+}
+
+// Set implements the flag.Value interface.
+func (f formFields) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("Invalid field %q : Expected name=value", value)
+	}
+
+	url.Values(f).Add(parts[0], parts[1])
+	return nil
+}
+
+// listFlag collects repeated flags into a list.
+type listFlag []string
+
+// String implements the flag.Value interface.
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set implements the flag.Value interface.
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+//==============================================================================
+
+// options defines the settings shared by the spidy commands, which are set
+// through flags and overridden by any SPIDY_ environment variables.
+type options struct {
+	target      string
+	all         bool
+	workers     int
+	extWorkers  int
+	timeout     int
+	cookies     string
+	loginURL    string
+	loginExpect string
+	loginCookie string
+	loginFields formFields
+	caFile      string
+	insecure    listFlag
+	certExpiry  int
+	scopeMode   string
+	scopePrefix string
+	scopeHosts  listFlag
+	seeds       listFlag
+	manifest    string
+	suppress    string
+	integrity   bool
+	seo         bool
+	seoSkip     listFlag
+	seoTitle    int
+	a11y        bool
+	a11ySkip    listFlag
+	dups        bool
+	dupMin      float64
+	graph       string
+	graphFormat string
+	maxPages    int
+	maxRequests int
+	maxBytes    int64
+	maxDuration time.Duration
+	maxBody     listFlag
+	store       string
+	storeDir    string
+	storeRun    string
+	stores      []io.Closer
+	depth       int
+	priority    listFlag
+	patterns    listFlag
+	soft404     bool
+	softTitles  listFlag
+	softBodies  listFlag
+	softMin     float64
+	dir         string
+	base        string
+	format      string
+	output      string
+}
+
+// register registers the flags of the options on the giving flag set.
+func (o *options) register(fs *flag.FlagSet) {
+	o.loginFields = make(formFields)
+
+	fs.StringVar(&o.target, "url", "", "Target URL for crawling")
+	fs.IntVar(&o.workers, "workers", 100, "Maximum workers to use in crawling")
+	fs.IntVar(&o.timeout, "timeout", 10000, "Maximum timeout before HEAD requests fails in milliseconds")
+	fs.BoolVar(&o.all, "externals", false, "Check links beyond the scope of the crawl with a single request each, without crawling them")
+	fs.IntVar(&o.extWorkers, "external-workers", 0, "Maximum workers to use in checking external links, defaults to -workers")
+	fs.StringVar(&o.cookies, "cookies", "", "Netscape cookies.txt file to load session cookies from")
+	fs.StringVar(&o.loginURL, "login", "", "URL to post the login form to before crawling")
+	fs.StringVar(&o.loginExpect, "login-expect", "", "Text expected in the login response on success")
+	fs.StringVar(&o.loginCookie, "login-cookie", "", "Name of the cookie expected to be set by the login")
+	fs.Var(o.loginFields, "login-field", "Login form field as name=value, can be repeated")
+	fs.StringVar(&o.caFile, "ca-file", "", "PEM bundle of CAs to trust in addition to the system's")
+	fs.Var(&o.insecure, "insecure-host", "Host pattern whose certificates need not verify, can be repeated")
+	fs.IntVar(&o.certExpiry, "cert-expiry", 30, "Days before a certificate expires to warn at")
+	fs.StringVar(&o.scopeMode, "scope", spidy.ScopeHost, "Scope of pages to crawl: host, subdomains, domain, path or hosts")
+	fs.StringVar(&o.scopePrefix, "scope-prefix", "", "Path prefix of pages to crawl for the path scope")
+	fs.Var(&o.scopeHosts, "scope-host", "Host pattern to crawl for the hosts scope, can be repeated")
+	fs.Var(&o.seeds, "seed", "Additional URL to crawl within the same scope, can be repeated")
+	fs.StringVar(&o.manifest, "manifest", "", "JSON manifest of named sites to crawl together")
+	fs.BoolVar(&o.integrity, "integrity", false, "Validate the content of the scripts, stylesheets and images pages load")
+	fs.BoolVar(&o.seo, "seo", false, "Audit pages for common SEO issues")
+	fs.Var(&o.seoSkip, "seo-skip", "SEO rule not to run, can be repeated")
+	fs.IntVar(&o.seoTitle, "seo-max-title", 60, "Length in characters past which titles are too long")
+	fs.BoolVar(&o.a11y, "a11y", false, "Lint pages for common accessibility issues")
+	fs.Var(&o.a11ySkip, "a11y-skip", "Accessibility rule not to run, can be repeated")
+	fs.BoolVar(&o.dups, "duplicates", false, "Cluster pages with duplicate or near duplicate content")
+	fs.Float64Var(&o.dupMin, "duplicate-threshold", 0.9, "Similarity from 0 to 1 at which pages are near duplicates")
+	fs.StringVar(&o.graph, "graph", "", "File to export the internal link graph and its analytics to")
+	fs.StringVar(&o.graphFormat, "graph-format", spidy.FormatDOT, "Format of the link graph: dot, graphml or json")
+	fs.IntVar(&o.maxPages, "max-pages", 0, "Maximum pages to fetch before stopping the crawl")
+	fs.IntVar(&o.maxRequests, "max-requests", 0, "Maximum requests to make before stopping the crawl")
+	fs.Int64Var(&o.maxBytes, "max-bytes", 0, "Maximum bytes to download before stopping the crawl")
+	fs.DurationVar(&o.maxDuration, "max-duration", 0, "Maximum time to crawl for before stopping, e.g 30m")
+	fs.Var(&o.maxBody, "max-body", "Maximum body size as type=bytes, e.g text/html=1048576 or *=0 for unlimited, can be repeated")
+	fs.StringVar(&o.store, "store", "memory", "Where pending pages and visited links are held: memory, sharded or disk")
+	fs.StringVar(&o.storeDir, "store-dir", os.TempDir(), "Directory the disk store keeps its files in")
+	fs.IntVar(&o.depth, "depth", 0, "Maximum number of links from the seeds of the pages to crawl, 0 for unlimited")
+	fs.Var(&o.priority, "priority", "Priority to crawl pages in: depth or sitemap, can be repeated to add them up")
+	fs.Var(&o.patterns, "priority-pattern", "URL pattern to crawl pages first as pattern=weight, e.g /docs/=10, can be repeated")
+	fs.BoolVar(&o.soft404, "soft-404", false, "Detect pages which answer with a 2xx but read as not found")
+	fs.Var(&o.softTitles, "soft-404-title", "Pattern of the titles of not found pages, can be repeated")
+	fs.Var(&o.softBodies, "soft-404-body", "Pattern of the text of not found pages, can be repeated")
+	fs.Float64Var(&o.softMin, "soft-404-threshold", 0.5, "Confidence from 0 to 1 at which soft 404s are reported")
+	fs.StringVar(&o.suppress, "suppress", "", "JSON file of known or accepted failures to suppress")
+	fs.StringVar(&o.dir, "dir", "", "Directory of a static site to crawl from disk in place of a web server")
+	fs.StringVar(&o.base, "base", "", "Base URL the files of -dir are served under, defaults to -url")
+	fs.StringVar(&o.format, "format", spidy.FormatText, "Format of the report: text, json or csv")
+	fs.StringVar(&o.output, "output", "", "File to write the report to, defaults to stdout")
+}
+
+// env overrides the options with any SPIDY_ environment variables set.
+func (o *options) env() {
+	if err := cfg.Init(cfg.EnvProvider{Namespace: "SPIDY"}); err != nil {
+		return
+	}
+
+	if tu, err := cfg.String("TARGET_URL"); err == nil {
+		o.target = tu
+	}
+
+	if tm, err := cfg.Int("HTTP_TIMEOUT"); err == nil {
+		o.timeout = tm
+	}
+
+	if ap, err := cfg.Bool("EXTERNAL_LINKS"); err == nil {
+		o.all = ap
+	}
+
+	if wo, err := cfg.Int("MAX_WORKERS"); err == nil {
+		o.workers = wo
+	}
+
+	if ew, err := cfg.Int("EXTERNAL_WORKERS"); err == nil {
+		o.extWorkers = ew
+	}
+
+	if cf, err := cfg.String("COOKIES"); err == nil {
+		o.cookies = cf
+	}
+
+	if lu, err := cfg.String("LOGIN_URL"); err == nil {
+		o.loginURL = lu
+
+		lf, _ := cfg.String("LOGIN_FIELDS")
+		fields, _ := url.ParseQuery(lf)
+		o.loginFields = formFields(fields)
+
+		if le, err := cfg.String("LOGIN_EXPECT"); err == nil {
+			o.loginExpect = le
+		}
+
+		if lc, err := cfg.String("LOGIN_COOKIE"); err == nil {
+			o.loginCookie = lc
+		}
+	}
+
+	if ca, err := cfg.String("CA_FILE"); err == nil {
+		o.caFile = ca
+	}
+
+	if ih, err := cfg.String("INSECURE_HOSTS"); err == nil {
+		o.insecure = strings.Split(ih, ",")
+	}
+
+	if ce, err := cfg.Int("CERT_EXPIRY"); err == nil {
+		o.certExpiry = ce
+	}
+
+	if sm, err := cfg.String("SCOPE"); err == nil {
+		o.scopeMode = sm
+	}
+
+	if sh, err := cfg.String("SCOPE_HOSTS"); err == nil {
+		o.scopeHosts = strings.Split(sh, ",")
+	}
+
+	if sp, err := cfg.String("SCOPE_PREFIX"); err == nil {
+		o.scopePrefix = sp
+	}
+
+	if mf, err := cfg.String("MANIFEST"); err == nil {
+		o.manifest = mf
+	}
+
+	if ri, err := cfg.Bool("INTEGRITY"); err == nil {
+		o.integrity = ri
+	}
+
+	if se, err := cfg.Bool("SEO"); err == nil {
+		o.seo = se
+	}
+
+	if ss, err := cfg.String("SEO_SKIP"); err == nil {
+		o.seoSkip = strings.Split(ss, ",")
+	}
+
+	if ay, err := cfg.Bool("A11Y"); err == nil {
+		o.a11y = ay
+	}
+
+	if as, err := cfg.String("A11Y_SKIP"); err == nil {
+		o.a11ySkip = strings.Split(as, ",")
+	}
+
+	if du, err := cfg.Bool("DUPLICATES"); err == nil {
+		o.dups = du
+	}
+
+	if gf, err := cfg.String("GRAPH"); err == nil {
+		o.graph = gf
+	}
+
+	if gt, err := cfg.String("GRAPH_FORMAT"); err == nil {
+		o.graphFormat = gt
+	}
+
+	if mp, err := cfg.Int("MAX_PAGES"); err == nil {
+		o.maxPages = mp
+	}
+
+	if mr, err := cfg.Int("MAX_REQUESTS"); err == nil {
+		o.maxRequests = mr
+	}
+
+	if mb, err := cfg.Int("MAX_BYTES"); err == nil {
+		o.maxBytes = int64(mb)
+	}
+
+	if md, err := cfg.String("MAX_DURATION"); err == nil {
+		if d, err := time.ParseDuration(md); err == nil {
+			o.maxDuration = d
+		}
+	}
+
+	if mb, err := cfg.String("MAX_BODY"); err == nil {
+		o.maxBody = strings.Split(mb, ",")
+	}
+
+	if st, err := cfg.String("STORE"); err == nil {
+		o.store = st
+	}
+
+	if sd, err := cfg.String("STORE_DIR"); err == nil {
+		o.storeDir = sd
+	}
+
+	if dp, err := cfg.Int("DEPTH"); err == nil {
+		o.depth = dp
+	}
+
+	if pr, err := cfg.String("PRIORITY"); err == nil {
+		o.priority = strings.Split(pr, ",")
+	}
+
+	if pp, err := cfg.String("PRIORITY_PATTERN"); err == nil {
+		o.patterns = strings.Split(pp, ",")
+	}
+
+	if s4, err := cfg.Bool("SOFT_404"); err == nil {
+		o.soft404 = s4
+	}
+
+	if sf, err := cfg.String("SUPPRESSIONS"); err == nil {
+		o.suppress = sf
+	}
+
+	if sd, err := cfg.String("SITE_DIR"); err == nil {
+		o.dir = sd
+	}
+
+	if bu, err := cfg.String("BASE_URL"); err == nil {
+		o.base = bu
+	}
+
+	if rf, err := cfg.String("REPORT_FORMAT"); err == nil {
+		o.format = rf
+	}
+
+	if ro, err := cfg.String("REPORT_FILE"); err == nil {
+		o.output = ro
+	}
+}
+
+// config returns the spidy config for the options.
+func (o *options) config() (spidy.Config, error) {
+	scope := spidy.Scope{Mode: o.scopeMode, Hosts: o.scopeHosts, Prefix: o.scopePrefix}
+
+	var seeds []spidy.Seed
+	for _, u := range o.seeds {
+		seeds = append(seeds, spidy.Seed{URL: u, Scope: scope})
+	}
+
+	if o.manifest != "" {
+		m, err := spidy.LoadManifest(o.manifest)
+		if err != nil {
+			return spidy.Config{}, err
+		}
+
+		seeds = append(seeds, m.Seeds()...)
+	}
+
+	var suppressions []spidy.Suppression
+	if o.suppress != "" {
+		var err error
+		if suppressions, err = spidy.LoadSuppressions(o.suppress); err != nil {
+			return spidy.Config{}, err
+		}
+	}
+
+	var soft404 *spidy.Soft404
+	if o.soft404 {
+		soft404 = &spidy.Soft404{
+			Probe:     true,
+			Titles:    o.softTitles,
+			Bodies:    o.softBodies,
+			Threshold: o.softMin,
+		}
+	}
+
+	var seo *spidy.SEO
+	if o.seo {
+		seo = &spidy.SEO{Skip: o.seoSkip, MaxTitle: o.seoTitle}
+	}
+
+	var a11y *spidy.A11y
+	if o.a11y {
+		a11y = &spidy.A11y{Skip: o.a11ySkip}
+	}
+
+	var dups *spidy.Duplicates
+	if o.dups {
+		dups = &spidy.Duplicates{Threshold: o.dupMin}
+	}
+
+	// Body limits override the defaults per content type.
+	var limits map[string]int64
+	if len(o.maxBody) > 0 {
+		limits = make(map[string]int64)
+		for mediaType, limit := range spidy.DefaultBodyLimits {
+			limits[mediaType] = limit
+		}
+
+		for _, mb := range o.maxBody {
+			parts := strings.SplitN(mb, "=", 2)
+			if len(parts) != 2 {
+				return spidy.Config{}, fmt.Errorf("Invalid body limit[%s] : Expected type=bytes", mb)
+			}
+
+			limit, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+			if err != nil {
+				return spidy.Config{}, fmt.Errorf("Invalid body limit[%s] : %s", mb, err)
+			}
+
+			limits[strings.ToLower(strings.TrimSpace(parts[0]))] = limit
+		}
+	}
+
+	ms := time.Duration(o.timeout) * time.Millisecond
+	client := &http.Client{Timeout: ms}
+
+	// Priorities add up, pages are crawled breadth first without any.
+	// Sitemaps are read by the crawl, through the client it prepares.
+	var priorities []spidy.PriorityFunc
+	var sitemap bool
+	for _, pr := range o.priority {
+		switch strings.TrimSpace(pr) {
+		case "depth":
+			priorities = append(priorities, spidy.DepthPriority)
+		case "sitemap":
+			sitemap = true
+		default:
+			return spidy.Config{}, fmt.Errorf("Invalid priority[%s]", pr)
+		}
+	}
+
+	if len(o.patterns) > 0 {
+		weights := make(map[string]float64)
+		for _, pp := range o.patterns {
+			i := strings.LastIndex(pp, "=")
+			if i < 0 {
+				return spidy.Config{}, fmt.Errorf("Invalid priority pattern[%s] : Expected pattern=weight", pp)
+			}
+
+			weight, err := strconv.ParseFloat(strings.TrimSpace(pp[i+1:]), 64)
+			if err != nil {
+				return spidy.Config{}, fmt.Errorf("Invalid priority pattern[%s] : %s", pp, err)
+			}
+
+			weights[pp[:i]] = weight
+		}
+
+		pf, err := spidy.PatternPriority(weights)
+		if err != nil {
+			return spidy.Config{}, err
+		}
+
+		priorities = append(priorities, pf)
+	}
+
+	var priority spidy.PriorityFunc
+	if len(priorities) > 0 {
+		priority = spidy.CombinePriority(priorities...)
+	}
+
+	var frontier spidy.Frontier
+	var visited spidy.VisitedSet
+
+	switch o.store {
+	case "", "memory":
+		// The crawl holds both in memory by default.
+
+	case "sharded":
+		visited = spidy.NewShardedVisited(o.workers)
+
+	case "disk":
+		// Each run keeps its files in a directory of its own, so runs
+		// sharing a store directory don't use each other's files.
+		dir, err := ioutil.TempDir(o.storeDir, "spidy-store")
+		if err != nil {
+			return spidy.Config{}, err
+		}
+
+		o.storeRun = dir
+
+		df, err := spidy.NewDiskFrontier(filepath.Join(dir, "frontier.log"))
+		if err != nil {
+			o.close()
+			return spidy.Config{}, err
+		}
+
+		o.stores = append(o.stores, df)
+
+		dv, err := spidy.NewDiskVisited(filepath.Join(dir, "visited.log"), 0, 0)
+		if err != nil {
+			o.close()
+			return spidy.Config{}, err
+		}
+
+		o.stores = append(o.stores, dv)
+		frontier, visited = df, dv
+
+	default:
+		return spidy.Config{}, fmt.Errorf("Invalid store[%s]", o.store)
+	}
+
+	var login *spidy.Login
+	if o.loginURL != "" {
+		login = &spidy.Login{
+			URL:    o.loginURL,
+			Fields: url.Values(o.loginFields),
+			Expect: o.loginExpect,
+			Cookie: o.loginCookie,
+		}
+	}
+
+	// A static site is crawled from its base URL when no URL is given.
+	target := o.target
+	if target == "" {
+		target = o.base
+	}
+
+	conf := spidy.Config{
+		Client:  client,
+		URL:     target,
+		All:     o.all,
+		Workers: o.workers,
+		Depth:   o.depth,
+		Events:  events,
+		Login:   login,
+		Cookies: o.cookies,
+
+		ExternalWorkers: o.extWorkers,
+
+		CAFile:        o.caFile,
+		InsecureHosts: o.insecure,
+		CertExpiry:    o.certExpiry,
+
+		Scope: scope,
+		Seeds: seeds,
+
+		Dir:  o.dir,
+		Base: o.base,
+
+		Suppressions:      suppressions,
+		Soft404:           soft404,
+		Integrity:         o.integrity,
+		SEO:               seo,
+		A11y:              a11y,
+		Duplicates:        dups,
+		Graph:             o.graph != "",
+		BodyLimits:        limits,
+		Frontier:          frontier,
+		Visited:           visited,
+		Priority:          priority,
+		PrioritizeSitemap: sitemap,
+
+		Budget: spidy.Budget{
+			Pages:    o.maxPages,
+			Requests: o.maxRequests,
+			Bytes:    o.maxBytes,
+			Duration: o.maxDuration,
+		},
+	}
+
+	return conf, nil
+}
+
+// close closes the stores the config of the options holds pending pages and
+// visited links in, removing the files of the run from the disk store.
+func (o *options) close() error {
+	var first error
+	for _, s := range o.stores {
+		if err := s.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	if o.storeRun != "" {
+		if err := os.RemoveAll(o.storeRun); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	o.stores = nil
+	o.storeRun = ""
+	return first
+}
+
+// writeReport writes the report in the format of the options to the output
+// file, or to stdout if no file is given.
+func (o *options) writeReport(report *spidy.Report) error {
+	return o.write(func(w io.Writer) error {
+		return spidy.WriteReport(w, report, o.format)
+	})
+}
+
+// writeGraph writes the link graph of the report in the graph format of the
+// options to the graph file, if one is given.
+func (o *options) writeGraph(report *spidy.Report) error {
+	if o.graph == "" || report.Graph == nil {
+		return nil
+	}
+
+	out, err := os.Create(o.graph)
+	if err != nil {
+		return err
+	}
+
+	if err := spidy.WriteGraph(out, report.Graph, o.graphFormat); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// write calls the giving function to write to the output file, or to stdout
+// if no file is given.
+func (o *options) write(fn func(w io.Writer) error) error {
+	if o.output == "" {
+		return fn(os.Stdout)
+	}
+
+	out, err := os.Create(o.output)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(out); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -1,13 +1,11 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"net/http"
 	"os"
-	"time"
 
-	"github.com/ardanlabs/kit/cfg"
 	"github.com/ardanlabs/kit/log"
 	"github.com/ardanlabs/spidy/spidy"
 )
@@ -36,118 +34,195 @@ func (Events) Event(context interface{}, event string, format string, data ...in
 //==============================================================================
 
 func main() {
-	log.Init(os.Stdout, func() int { return log.DEV }, log.Ldefault)
+	// Logs go to stderr, as stdout carries the report unless an output file
+	// is given.
+	log.Init(os.Stderr, func() int { return log.DEV }, log.Ldefault)
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "watch":
+			runWatch(os.Args[2:])
+			return
+		}
+	}
+
+	runCrawl(os.Args[1:])
+}
 
-	var link = flag.String("url", "", "Target URL for crawling")
-	var workerCount = flag.Int("workers", 100, "Maximum workers to use in crawling")
-	var timeout = flag.Int("timeout", 10000, "Maximum timeout before HEAD requests fails in milliseconds")
-	var doExternals = flag.Bool("externals", false, "flag to crawl none external links. Defaults to true")
+// runCrawl crawls the configured URLs, seeds and manifest sites for dead
+// links.
+func runCrawl(args []string) {
+	var opts options
 
-	flag.Parse()
+	fs := flag.NewFlagSet("spidy", flag.ExitOnError)
+	opts.register(fs)
 
-	flag.Usage = func() {
-		fmt.Println(`
+	fs.Usage = func() {
+		fmt.Print(`
 Spidy - A simple deadlink finder.
 
 Flags:
 
- -w "Maximum workers to be used for crawling pages, defaults to 100"
  -url "URL to crawl for dead links"
- -hostOnly "A boolean flag which allows setting whether external links should be considered"
+ -workers "Maximum workers to use in crawling, defaults to 100"
+ -timeout "Maximum timeout before requests fail in milliseconds, defaults to 10000"
+ -externals "Check links beyond the scope of the crawl with a single request each, without crawling them"
+ -external-workers "Maximum workers to use in checking external links, defaults to -workers"
+ -cookies "Netscape cookies.txt file to load session cookies from"
+ -login "URL to post the login form to before crawling"
+ -login-field "Login form field as name=value, can be repeated"
+ -login-expect "Text expected in the login response on success"
+ -login-cookie "Name of the cookie expected to be set by the login"
+ -ca-file "PEM bundle of CAs to trust in addition to the system's"
+ -insecure-host "Host pattern whose certificates need not verify, can be repeated"
+ -cert-expiry "Days before a certificate expires to warn at, defaults to 30"
+ -scope "Scope of pages to crawl: host, subdomains, domain, path or hosts, defaults to host"
+ -scope-host "Host pattern to crawl for the hosts scope, can be repeated"
+ -scope-prefix "Path prefix of pages to crawl for the path scope, defaults to the URL's directory"
+ -seed "Additional URL to crawl within the same scope, can be repeated"
+ -manifest "JSON manifest of named sites to crawl together"
+ -integrity "Validate the content of the scripts, stylesheets and images pages load"
+ -seo "Audit pages for common SEO issues"
+ -seo-skip "SEO rule not to run, can be repeated"
+ -seo-max-title "Length in characters past which titles are too long, defaults to 60"
+ -a11y "Lint pages for common accessibility issues"
+ -a11y-skip "Accessibility rule not to run, can be repeated"
+ -duplicates "Cluster pages with duplicate or near duplicate content"
+ -duplicate-threshold "Similarity from 0 to 1 at which pages are near duplicates"
+ -graph "File to export the internal link graph and its analytics to"
+ -graph-format "Format of the link graph: dot, graphml or json, defaults to dot"
+ -max-pages "Maximum pages to fetch before stopping the crawl"
+ -max-requests "Maximum requests to make before stopping the crawl"
+ -max-bytes "Maximum bytes to download before stopping the crawl"
+ -max-duration "Maximum time to crawl for before stopping, e.g 30m"
+ -max-body "Maximum body size as type=bytes, e.g text/html=1048576 or *=0 for unlimited, can be repeated"
+ -store "Where pending pages and visited links are held: memory, sharded or disk, defaults to memory"
+ -store-dir "Directory the disk store keeps its files in, defaults to the temporary directory"
+ -depth "Maximum number of links from the seeds of the pages to crawl, defaults to 0 for unlimited"
+ -priority "Priority to crawl pages in: depth or sitemap, can be repeated to add them up"
+ -priority-pattern "URL pattern to crawl pages first as pattern=weight, e.g /docs/=10, can be repeated"
+ -soft-404 "Detect pages which answer with a 2xx but read as not found"
+ -soft-404-title "Pattern of the titles of not found pages, can be repeated"
+ -soft-404-body "Pattern of the text of not found pages, can be repeated"
+ -soft-404-threshold "Confidence from 0 to 1 at which soft 404s are reported, defaults to 0.5"
+ -suppress "JSON file of known or accepted failures to suppress"
+ -dir "Directory of a static site to crawl from disk in place of a web server"
+ -base "Base URL the files of -dir are served under, defaults to -url"
+ -format "Format of the report: text, json or csv, defaults to text"
+ -output "File to write the report to, defaults to stdout"
+
+Commands:
+
+ check "Checks a list of URLs without crawling them, see spidy check -h"
+ diff "Compares the reports of two runs, see spidy diff -h"
+ watch "Re-runs a crawl on a schedule, writing only its changes, see spidy watch -h"
 
 Usage:
 
-	// To crawl the giving url and no external links as well
+	// To crawl the giving url without checking external links
 	spidy -url http://golang.org
-	spidy -url http://golang.org -externals true
 
-	// To crawl the giving url and external links as well
-	spidy -url http://golang.org -externals false
+	// To crawl the giving url, checking external links once each without crawling them
+	spidy -url http://golang.org -externals
+
+	// To check external links on at most 20 workers of their own
+	spidy -url http://golang.org -externals -external-workers 20
 
 	// To crawl the giving url and set maximum possible workers and a custom timeout
 	// for HEAD requests in milliseconds
 	spidy -url http://golang.org -workers 300 -timeout 300
 
-`)
-	}
+	// To login through a form before crawling
+	spidy -url http://example.com -login http://example.com/login -login-field user=bob -login-field pass=secret -login-cookie session
 
-	var target string
-	var allPaths bool
+	// To trust an internal CA and skip verification for staging hosts
+	spidy -url https://intranet.example.com -ca-file internal-ca.pem -insecure-host "*.staging.example.com"
 
-	workers := 100
-	httpTimeout := 10000
+	// To crawl the docs section of a site, checking but not crawling links beyond it
+	spidy -url http://golang.org/doc/ -scope path -externals
 
-	if err := cfg.Init(cfg.EnvProvider{Namespace: "SPIDY"}); err == nil {
+	// To crawl a site and its subdomains
+	spidy -url http://golang.org -scope subdomains
 
-		cfg.MustString("TARGET_URL")
+	// To crawl several sites together, sharing the checks of external links
+	spidy -url http://golang.org -seed http://blog.golang.org -externals
+	spidy -manifest sites.json -format json -output report.json
 
-		if tm, err := cfg.Int("HTTP_TIMEOUT"); err != nil {
-			httpTimeout = tm
-		}
+	// To audit the pages of a site for SEO issues, except orphaned pages
+	spidy -url http://example.com -seo -seo-skip orphan
 
-		if ap, err := cfg.Bool("EXTERNAL_LINKS"); err != nil {
-			allPaths = ap
-		}
+	// To lint the pages of a site for accessibility issues
+	spidy -url http://example.com -a11y -format csv -output a11y.csv
 
-		if wo, err := cfg.Int("MAX_WORKERS"); err != nil {
-			workers = wo
-		}
+	// To export the link graph of a site for Graphviz
+	spidy -url http://example.com -graph site.dot
+	dot -Tsvg site.dot -o site.svg
 
-	} else {
+	// To crawl at most 5000 pages of a huge site for at most half an hour
+	spidy -url http://example.com -max-pages 5000 -max-duration 30m
 
-		if *link == "" {
-			events.ErrorEvent(context, "main", err, "Configuration Error : Initialization Failed")
-			os.Exit(1)
-		}
+	// To crawl a site of millions of pages in bounded memory
+	spidy -url http://example.com -store disk -store-dir /var/tmp/spidy
+
+	// To crawl the documentation of a site first within a budget of 1000 pages
+	spidy -url http://example.com -max-pages 1000 -priority-pattern /docs/=10
 
-		target = *link
-		workers = *workerCount
-		allPaths = *doExternals
-		httpTimeout = *timeout
+	// To crawl a site reporting pages which answer 200 but read as not found
+	spidy -url http://example.com -soft-404 -soft-404-title "(?i)oops"
+
+	// To crawl a site, suppressing known or accepted failures until they expire
+	spidy -url http://golang.org -externals -suppress suppressions.json
+
+	// To crawl the build output of a static site before deploying it
+	spidy -dir ./public -base https://docs.example.com -externals
+
+`)
 	}
 
-	start := time.Now()
-	ms := time.Duration(httpTimeout) * time.Millisecond
+	fs.Parse(args)
+	opts.env()
 
-	conf := spidy.Config{
-		Client:  &http.Client{Timeout: ms},
-		URL:     target,
-		All:     allPaths,
-		Workers: workers,
-		Depth:   -1,
-		Events:  events,
+	if opts.target == "" && opts.base == "" && opts.manifest == "" {
+		events.ErrorEvent(context, "main", errors.New("No URL, base or manifest given"), "Configuration Error : Initialization Failed")
+		os.Exit(1)
 	}
 
-	deadlinks, err := spidy.Run(context, &conf)
+	conf, err := opts.config()
 	if err != nil {
-		events.ErrorEvent(context, "main", err, "Completed")
+		opts.close()
+		events.ErrorEvent(context, "main", err, "Configuration Error : Initialization Failed")
 		os.Exit(1)
 	}
 
-	end := time.Now().UTC()
+	report, err := spidy.Crawl(context, &conf)
 
-	fmt.Println("--------------------Timelapse-------------------------------")
-	fmt.Printf(`
-Start Time: %s
-End Time: %s
-Duration: %s
-`, start, end, end.Sub(start))
+	if err := opts.close(); err != nil {
+		events.ErrorEvent(context, "main", err, "Closing Stores")
+	}
 
-	if len(deadlinks) > 0 {
-		fmt.Println("--------------------DEAD LINKS------------------------------")
+	if err != nil {
+		events.ErrorEvent(context, "main", err, "Completed")
+		os.Exit(1)
+	}
 
-		for _, f := range deadlinks {
-			fmt.Printf(`
-URL: %s
-Status Code: %d
-Error: %s
+	if err := opts.writeReport(report); err != nil {
+		events.ErrorEvent(context, "main", err, "Completed")
+		os.Exit(1)
+	}
 
-`, f.Link, f.Status, f.Error)
-		}
-		fmt.Println("------------------------------------------------------------")
+	if err := opts.writeGraph(report); err != nil {
+		events.ErrorEvent(context, "main", err, "Completed")
+		os.Exit(1)
+	}
 
+	// Warnings are reported but don't fail the crawl.
+	if report.Failed() {
 		os.Exit(-1)
 	}
-
-	fmt.Println("------------------------------------------------------------")
 }
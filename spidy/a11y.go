@@ -0,0 +1,304 @@
+package spidy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Rules of the accessibility lint, findings of each rule are reported with
+// the kind "a11y-" followed by the rule, e.g a11y-image-alt.
+const (
+	A11yImageAlt     = "image-alt"
+	A11yLinkName     = "link-name"
+	A11yButtonName   = "button-name"
+	A11yHeadingOrder = "heading-order"
+	A11yInputLabel   = "input-label"
+	A11yHTMLLang     = "html-lang"
+	A11yDuplicateID  = "duplicate-id"
+)
+
+// a11yRules lists every rule of the accessibility lint.
+var a11yRules = []string{
+	A11yImageAlt, A11yLinkName, A11yButtonName, A11yHeadingOrder,
+	A11yInputLabel, A11yHTMLLang, A11yDuplicateID,
+}
+
+// A11y configures the accessibility lint of the pages of a crawl. Every rule
+// is run unless skipped.
+type A11y struct {
+	Skip []string // Rules not to run.
+}
+
+//==============================================================================
+
+// a11yInspector lints the pages of a crawl for common accessibility issues
+// found within their markup. It implements the PageInspector interface.
+type a11yInspector struct {
+	rules map[string]bool
+
+	ml    sync.Mutex
+	pages map[string]bool
+}
+
+// newA11yInspector returns a new a11yInspector for the config, or nil if
+// pages are not linted.
+func newA11yInspector(c *Config) (*a11yInspector, error) {
+	if c.A11y == nil {
+		return nil, nil
+	}
+
+	ai := a11yInspector{
+		rules: make(map[string]bool),
+		pages: make(map[string]bool),
+	}
+
+	for _, rule := range a11yRules {
+		ai.rules[rule] = true
+	}
+
+	for _, rule := range c.A11y.Skip {
+		if !ai.rules[rule] {
+			return nil, fmt.Errorf("Invalid accessibility rule[%s]", rule)
+		}
+
+		ai.rules[rule] = false
+	}
+
+	return &ai, nil
+}
+
+// Inspect implements the PageInspector interface.
+func (ai *a11yInspector) Inspect(context interface{}, pg *Page) []LinkReport {
+
+	// Links which redirect to a page already linted need not lint it again.
+	page := pg.URL.String()
+
+	ai.ml.Lock()
+	linted := ai.pages[page]
+	ai.pages[page] = true
+	ai.ml.Unlock()
+
+	if linted {
+		return nil
+	}
+
+	doc := pg.Document
+	lint := a11yLint{rules: ai.rules, doc: doc, ids: make(map[string]*goquery.Selection)}
+
+	doc.Find("[id]").Each(func(_ int, s *goquery.Selection) {
+		id, _ := s.Attr("id")
+		if prev, ok := lint.ids[id]; ok {
+			lint.ids[id] = prev.AddSelection(s)
+			return
+		}
+
+		lint.ids[id] = s
+	})
+
+	if lang, _ := doc.Find("html").First().Attr("lang"); strings.TrimSpace(lang) == "" {
+		lint.add(A11yHTMLLang, "html", "", "Page has no lang attribute on its html element")
+	}
+
+	doc.Find("img").Each(func(_ int, s *goquery.Selection) {
+		if _, ok := s.Attr("alt"); ok || decorative(s) {
+			return
+		}
+
+		lint.add(A11yImageAlt, "img", describeElement(s, "src"), "Image has no alt attribute")
+	})
+
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		if decorative(s) || lint.name(s) != "" {
+			return
+		}
+
+		lint.add(A11yLinkName, "a", describeElement(s, "href"), "Link has no accessible name")
+	})
+
+	doc.Find("button, input[type=button], input[type=submit], input[type=reset], input[type=image]").Each(func(_ int, s *goquery.Selection) {
+		if decorative(s) || lint.name(s) != "" {
+			return
+		}
+
+		// Submit and reset inputs are named by the browser by default.
+		if typ, _ := s.Attr("type"); goquery.NodeName(s) == "input" && (strings.EqualFold(typ, "submit") || strings.EqualFold(typ, "reset")) {
+			if _, ok := s.Attr("value"); !ok {
+				return
+			}
+		}
+
+		lint.add(A11yButtonName, goquery.NodeName(s), describeElement(s, "id"), "Button has no accessible name")
+	})
+
+	lint.headings()
+	lint.labels()
+
+	var ids []string
+	for id, s := range lint.ids {
+		if s.Length() > 1 && id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		lint.add(A11yDuplicateID, "*[id]", fmt.Sprintf("id=%q", id), fmt.Sprintf("Id %q is used by %d elements", id, lint.ids[id].Length()))
+	}
+
+	return lint.findings
+}
+
+//==============================================================================
+
+// a11yLint defines the lint of a single page.
+type a11yLint struct {
+	rules    map[string]bool
+	doc      *goquery.Document
+	ids      map[string]*goquery.Selection
+	findings []LinkReport
+}
+
+// add adds a finding of the giving rule for an element if the rule is run.
+func (l *a11yLint) add(rule string, tag string, reference string, msg string) {
+	if !l.rules[rule] {
+		return
+	}
+
+	l.findings = append(l.findings, LinkReport{
+		Tag:       tag,
+		Reference: reference,
+		Error:     errors.New(msg),
+		Kind:      "a11y-" + rule,
+		Severity:  SeverityWarning,
+	})
+}
+
+// headings adds a finding for every heading which skips a level from the
+// heading before it, such as an h4 following an h2.
+func (l *a11yLint) headings() {
+	var prev int
+
+	l.doc.Find("h1, h2, h3, h4, h5, h6").Each(func(_ int, s *goquery.Selection) {
+		level := int(goquery.NodeName(s)[1] - '0')
+
+		if prev > 0 && level > prev+1 {
+			l.add(A11yHeadingOrder, goquery.NodeName(s), collapseSpace(s.Text()), fmt.Sprintf("Heading skips from h%d to h%d", prev, level))
+		}
+
+		prev = level
+	})
+}
+
+// labels adds a finding for every form control which has no label.
+func (l *a11yLint) labels() {
+	labelled := make(map[string]bool)
+
+	l.doc.Find("label[for]").Each(func(_ int, s *goquery.Selection) {
+		id, _ := s.Attr("for")
+		labelled[id] = true
+	})
+
+	l.doc.Find("input, select, textarea").Each(func(_ int, s *goquery.Selection) {
+		if typ, _ := s.Attr("type"); goquery.NodeName(s) == "input" {
+			switch strings.ToLower(typ) {
+			case "hidden", "button", "submit", "reset", "image":
+				return
+			}
+		}
+
+		if id, ok := s.Attr("id"); ok && labelled[id] {
+			return
+		}
+
+		if s.ParentsFiltered("label").Length() > 0 || l.name(s) != "" {
+			return
+		}
+
+		l.add(A11yInputLabel, goquery.NodeName(s), describeElement(s, "name"), "Form control has no label")
+	})
+}
+
+// name returns the accessible name of the giving element, from its
+// aria-labelledby, aria-label, text, images, value or title.
+func (l *a11yLint) name(s *goquery.Selection) string {
+	if ids, ok := s.Attr("aria-labelledby"); ok {
+		var names []string
+		for _, id := range strings.Fields(ids) {
+			if label, ok := l.ids[id]; ok {
+				names = append(names, collapseSpace(label.First().Text()))
+			}
+		}
+
+		if name := strings.TrimSpace(strings.Join(names, " ")); name != "" {
+			return name
+		}
+	}
+
+	if label, _ := s.Attr("aria-label"); strings.TrimSpace(label) != "" {
+		return strings.TrimSpace(label)
+	}
+
+	if text := collapseSpace(s.Text()); text != "" {
+		return text
+	}
+
+	var alts []string
+	s.Find("img[alt]").Each(func(_ int, img *goquery.Selection) {
+		alt, _ := img.Attr("alt")
+		alts = append(alts, strings.TrimSpace(alt))
+	})
+
+	if alt := strings.TrimSpace(strings.Join(alts, " ")); alt != "" {
+		return alt
+	}
+
+	// Button inputs are named by their value and image inputs by their alt,
+	// the value of other inputs is what's entered rather than a name.
+	if goquery.NodeName(s) == "input" {
+		typ, _ := s.Attr("type")
+
+		var attr string
+		switch strings.ToLower(typ) {
+		case "button", "submit", "reset":
+			attr = "value"
+		case "image":
+			attr = "alt"
+		}
+
+		if v, _ := s.Attr(attr); attr != "" && strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+
+	title, _ := s.Attr("title")
+	return strings.TrimSpace(title)
+}
+
+// decorative reports whether the giving element is hidden from assistive
+// technologies or marked as presentational.
+func decorative(s *goquery.Selection) bool {
+	if hidden, _ := s.Attr("aria-hidden"); strings.EqualFold(hidden, "true") {
+		return true
+	}
+
+	role, _ := s.Attr("role")
+	return strings.EqualFold(role, "presentation") || strings.EqualFold(role, "none")
+}
+
+// describeElement returns the giving attribute of an element as name="value"
+// to identify the element within its page, or an empty string if it has no
+// such attribute.
+func describeElement(s *goquery.Selection, attr string) string {
+	v, ok := s.Attr(attr)
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf("%s=%q", attr, v)
+}
@@ -0,0 +1,130 @@
+package spidy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// a11yPage defines a page with an accessible and an inaccessible element for
+// each of the accessibility rules.
+var a11yPage = `<html><head><title>Lint</title></head><body>
+	<h1>Lint</h1>
+	<h2>Section</h2>
+	<h4>Skipped</h4>
+	<h2>Back</h2>
+
+	<img src="/logo.png" alt="Logo">
+	<img src="/spacer.gif" alt="">
+	<img src="/chart.png">
+	<img src="/flourish.png" role="presentation">
+
+	<a href="/named">Named</a>
+	<a href="/labelled" aria-label="Labelled"></a>
+	<a href="/icon"><img src="/icon.png" alt="Icon"></a>
+	<a href="/empty"><img src="/icon.png" alt=""></a>
+
+	<button>Save</button>
+	<button id="close"></button>
+	<input type="submit">
+	<input type="button" value="">
+
+	<span id="name-label">Name</span>
+	<label for="email">Email</label>
+	<input id="email" type="email">
+	<label>Phone <input type="tel"></label>
+	<input aria-labelledby="name-label">
+	<input type="hidden" name="token">
+	<input name="search" value="prefilled">
+	<textarea name="notes"></textarea>
+
+	<div id="dup"></div><div id="dup"></div>
+</body></html>`
+
+// TestA11y tests linting the pages of a site for accessibility issues.
+func TestA11y(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to lint pages for accessibility issues")
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != "/" {
+				res.Header().Set("Content-Type", "image/png")
+				return
+			}
+
+			res.Header().Set("Content-Type", "text/html")
+			res.Write([]byte(a11yPage))
+		}))
+
+		defer server.Close()
+
+		lint := func(a11y *spidy.A11y) []string {
+			conf := spidy.Config{
+				Client:  &http.Client{Timeout: 30 * time.Second},
+				URL:     server.URL,
+				Workers: 10,
+				Depth:   -1,
+				Events:  events,
+				A11y:    a11y,
+			}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have crawled the site", tests.Success)
+
+			var found []string
+			for _, f := range report.Links {
+				found = append(found, f.Kind+" "+f.Tag+" "+f.Reference)
+			}
+
+			sort.Strings(found)
+			return found
+		}
+
+		t.Logf("\tWhen linting a page with every rule")
+		{
+			found := lint(&spidy.A11y{})
+
+			expected := []string{
+				`a11y-button-name button id="close"`,
+				`a11y-button-name input `,
+				`a11y-duplicate-id *[id] id="dup"`,
+				`a11y-heading-order h4 Skipped`,
+				`a11y-html-lang html `,
+				`a11y-image-alt img src="/chart.png"`,
+				`a11y-input-label input name="search"`,
+				`a11y-input-label textarea name="notes"`,
+				`a11y-link-name a href="/empty"`,
+			}
+
+			if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+				t.Errorf("\t%s\tShould have found the accessibility issues:\n%s\n\tbut got:\n%s", tests.Failed, strings.Join(expected, "\n"), strings.Join(found, "\n"))
+			} else {
+				t.Logf("\t%s\tShould have found the accessibility issues", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen linting a page with rules skipped")
+		{
+			found := lint(&spidy.A11y{Skip: []string{spidy.A11yInputLabel, spidy.A11yHTMLLang}})
+
+			if len(found) != 6 {
+				t.Errorf("\t%s\tShould have only run the rules which are not skipped but got %v", tests.Failed, found)
+			} else {
+				t.Logf("\t%s\tShould have only run the rules which are not skipped", tests.Success)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package spidy
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultBodyLimits are the maximum sizes in bytes of the response bodies read
+// per media type, with "*" for any other type.
+var DefaultBodyLimits = map[string]int64{
+	"text/html": 10 << 20,
+	"text/css":  2 << 20,
+	"*":         50 << 20,
+}
+
+// bodyLimit returns the maximum size of the body of a response with the
+// giving content type, or 0 if it is unlimited.
+func bodyLimit(c *Config, contentType string) int64 {
+	limits := c.BodyLimits
+	if limits == nil {
+		limits = DefaultBodyLimits
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	limit, ok := limits[strings.ToLower(mediaType)]
+	if !ok {
+		limit = limits["*"]
+	}
+
+	if limit <= 0 {
+		return 0
+	}
+
+	return limit
+}
+
+//==============================================================================
+
+// limitedBody reads a response body up to its limit, recording whether the
+// body went on past it.
+type limitedBody struct {
+	io.ReadCloser
+	limit     int64
+	left      int64
+	truncated bool
+}
+
+// limitBody replaces the body of the giving response with one which is cut
+// short at the limit for its content type, which is returned.
+func limitBody(res *http.Response, c *Config) *limitedBody {
+	limit := bodyLimit(c, res.Header.Get("Content-Type"))
+
+	lb := limitedBody{ReadCloser: res.Body, limit: limit, left: limit}
+	res.Body = &lb
+
+	return &lb
+}
+
+// Read implements the io.Reader interface.
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.limit == 0 {
+		return l.ReadCloser.Read(p)
+	}
+
+	// Reading a single byte past the limit tells whether the body was cut
+	// short or was exactly as long as the limit.
+	if l.left <= 0 {
+		var probe [1]byte
+		if n, _ := l.ReadCloser.Read(probe[:]); n > 0 {
+			l.truncated = true
+		}
+
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > l.left {
+		p = p[:l.left]
+	}
+
+	n, err := l.ReadCloser.Read(p)
+	l.left -= int64(n)
+
+	return n, err
+}
+
+//==============================================================================
+
+// streamLinks returns the links within the attributes, <style> blocks and
+// style attributes of the HTML read from r, as documentLinks does but
+// tokenizing the document as it is read rather than parsing it into a tree.
+// Links are resolved against the first <base href> if there is one, else the
+// URL of the document.
+func streamLinks(r io.Reader, page *url.URL) []pageLink {
+	var styles, attrs, links []pageLink
+	var base string
+	var inStyle, hasBase bool
+
+	z := html.NewTokenizer(r)
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			links = append(styles, append(attrs, links...)...)
+
+			index := page
+			if hasBase {
+				if uri, err := parsePath(strings.TrimSpace(base), page); err == nil {
+					index = uri
+				}
+			}
+
+			return resolveLinks(links, index)
+
+		case html.TextToken:
+			if inStyle {
+				styles = append(styles, cssLinks(string(z.Text()), "style", "url()")...)
+			}
+
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "style" {
+				inStyle = false
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			node := html.Node{Type: html.ElementNode, Data: token.Data, Attr: token.Attr}
+
+			if token.Data == "style" {
+				inStyle = true
+			}
+
+			if href, ok := getAttr(token.Attr, "href"); ok && token.Data == "base" && !hasBase {
+				base, hasBase = href.Val, true
+			}
+
+			for _, attr := range token.Attr {
+				if attr.Key == "style" {
+					attrs = append(attrs, cssLinks(attr.Val, token.Data, "style")...)
+				}
+
+				links = append(links, extractAttr(&node, attr)...)
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package spidy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+// TestBodyLimits tests cutting short response bodies past the limit for their
+// content type.
+func TestBodyLimits(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to limit the size of the bodies read")
+	{
+		page := `<html><body><a href="/early">Early</a>` + strings.Repeat("<p>padding</p>", 50) + `<a href="/late">Late</a></body></html>`
+
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/":
+				res.Header().Set("Content-Type", "text/html; charset=utf-8")
+				res.Write([]byte(page))
+			case "/early":
+				res.Header().Set("Content-Type", "text/html")
+				res.Write([]byte(`<html><body>Early</body></html>`))
+			default:
+				http.NotFound(res, req)
+			}
+		}))
+
+		defer server.Close()
+
+		crawl := func(limits map[string]int64) []string {
+			conf := spidy.Config{
+				Client:     &http.Client{Timeout: 30 * time.Second},
+				URL:        server.URL,
+				Workers:    10,
+				Depth:      -1,
+				Events:     events,
+				BodyLimits: limits,
+			}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+
+			var found []string
+			for _, f := range report.Links {
+				found = append(found, f.Kind+" "+string(f.Severity)+" "+strings.TrimPrefix(f.Link, server.URL))
+			}
+
+			sort.Strings(found)
+			return found
+		}
+
+		t.Logf("\tWhen crawling a page past the limit for HTML")
+		{
+			found := crawl(map[string]int64{"text/html": 200})
+
+			if strings.Join(found, ",") != "truncated-body warning /" {
+				t.Errorf("\t%s\tShould have reported the page as truncated without the links past the limit but got %v", tests.Failed, found)
+			} else {
+				t.Logf("\t%s\tShould have reported the page as truncated without the links past the limit", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen crawling a page within the limit for HTML")
+		{
+			found := crawl(map[string]int64{"text/html": int64(len(page)), "*": 10})
+
+			if strings.Join(found, ",") != "dead-link error /late" {
+				t.Errorf("\t%s\tShould have read the whole page but got %v", tests.Failed, found)
+			} else {
+				t.Logf("\t%s\tShould have read the whole page", tests.Success)
+			}
+		}
+	}
+}
+
+// TestStreamLinks tests extracting the links of pages as they are read
+// matches extracting them from their document.
+func TestStreamLinks(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to extract links without parsing documents")
+	{
+		page := `<html><head>
+			<base href="/docs/">
+			<style>body { background: url(bg.png) }</style>
+			<link rel="stylesheet" href="missing.css">
+			<meta http-equiv="refresh" content="5; url=next">
+		</head><body>
+			<a href="guide#intro">Guide</a>
+			<A HREF="upper">Upper</A>
+			<img src="a.png" srcset="a-2x.png 2x, a-3x.png 3x">
+			<div style="background-image: url('div.png')"></div>
+			<svg><use xlink:href="sprite.svg#icon"></use></svg>
+			<a href="mailto:someone@example.com">Mail</a>
+		</body></html>`
+
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != "/" {
+				http.NotFound(res, req)
+				return
+			}
+
+			res.Header().Set("Content-Type", "text/html")
+			res.Write([]byte(page))
+		}))
+
+		defer server.Close()
+
+		crawl := func(inspectors []spidy.PageInspector) []string {
+			conf := spidy.Config{
+				Client:     &http.Client{Timeout: 30 * time.Second},
+				URL:        server.URL,
+				Workers:    10,
+				Depth:      -1,
+				Events:     events,
+				Inspectors: inspectors,
+			}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+
+			var found []string
+			for _, f := range report.Links {
+				found = append(found, strings.TrimPrefix(f.Link, server.URL)+" "+f.Tag+" "+f.Reference)
+			}
+
+			sort.Strings(found)
+			return found
+		}
+
+		t.Logf("\tWhen crawling a page with and without inspectors")
+		{
+			streamed := crawl(nil)
+			parsed := crawl([]spidy.PageInspector{spidy.InspectorFunc(func(context interface{}, pg *spidy.Page) []spidy.LinkReport {
+				return nil
+			})})
+
+			if len(streamed) != 10 || strings.Join(streamed, "\n") != strings.Join(parsed, "\n") {
+				t.Errorf("\t%s\tShould have found the same links either way:\n%s\n\tbut got:\n%s", tests.Failed, strings.Join(parsed, "\n"), strings.Join(streamed, "\n"))
+			} else {
+				t.Logf("\t%s\tShould have found the same links either way", tests.Success)
+			}
+		}
+	}
+}
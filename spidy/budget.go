@@ -0,0 +1,216 @@
+package spidy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Budgets a crawl can exhaust.
+const (
+	BudgetPages    = "pages"
+	BudgetRequests = "requests"
+	BudgetBytes    = "bytes"
+	BudgetDuration = "duration"
+)
+
+// errBudgetExhausted is the error of requests refused once a budget of the
+// crawl is exhausted.
+var errBudgetExhausted = errors.New("Crawl budget exhausted")
+
+// Budget defines hard limits on a crawl, which stops cleanly with a partial
+// report once any of them is reached. Zero values are unlimited.
+type Budget struct {
+	Pages    int           // Pages fetched and parsed for links.
+	Requests int           // HTTP requests made, including HEAD requests.
+	Bytes    int64         // Bytes of response bodies downloaded.
+	Duration time.Duration // Wall-clock time of the crawl.
+}
+
+// BudgetReport defines what a crawl spent of its budget, which budget it
+// exhausted if any and how many links it left unexplored as a result.
+type BudgetReport struct {
+	Exhausted  string `json:"exhausted,omitempty"`
+	Pages      int64  `json:"pages"`
+	Requests   int64  `json:"requests"`
+	Bytes      int64  `json:"bytes"`
+	Unexplored int64  `json:"unexplored"`
+}
+
+//==============================================================================
+
+// crawlBudget tracks the spending of the budget of a crawl.
+type crawlBudget struct {
+	limits   Budget
+	deadline time.Time
+
+	pages      int64
+	requests   int64
+	bytes      int64
+	unexplored int64
+
+	ml        sync.Mutex
+	exhausted string
+}
+
+// newCrawlBudget returns a new crawlBudget with the giving limits, whose
+// duration starts now.
+func newCrawlBudget(limits Budget) *crawlBudget {
+	b := crawlBudget{limits: limits}
+
+	if limits.Duration > 0 {
+		b.deadline = time.Now().Add(limits.Duration)
+	}
+
+	return &b
+}
+
+// client returns a copy of the giving client whose requests are counted and
+// refused once the budget is spent.
+func (b *crawlBudget) client(c *http.Client) *http.Client {
+	client := *c
+	client.Transport = &budgetTransport{next: c.Transport, budget: b}
+	return &client
+}
+
+// page spends a page of the budget, reporting whether it could.
+func (b *crawlBudget) page() bool {
+	if b.spent() {
+		return false
+	}
+
+	if n := atomic.AddInt64(&b.pages, 1); b.limits.Pages > 0 && n > int64(b.limits.Pages) {
+		atomic.AddInt64(&b.pages, -1)
+		b.exhaust(BudgetPages)
+		return false
+	}
+
+	return true
+}
+
+// pagesLeft returns the pages left within the budget, or -1 if pages are
+// unlimited.
+func (b *crawlBudget) pagesLeft() int {
+	if b.limits.Pages <= 0 {
+		return -1
+	}
+
+	return b.limits.Pages - int(atomic.LoadInt64(&b.pages))
+}
+
+// request spends a request of the budget, reporting whether it could.
+func (b *crawlBudget) request() bool {
+	if b.spent() {
+		return false
+	}
+
+	if n := atomic.AddInt64(&b.requests, 1); b.limits.Requests > 0 && n > int64(b.limits.Requests) {
+		atomic.AddInt64(&b.requests, -1)
+		b.exhaust(BudgetRequests)
+		return false
+	}
+
+	return true
+}
+
+// read spends the giving number of downloaded bytes of the budget, any
+// further requests are refused once they exceed it.
+func (b *crawlBudget) read(n int) {
+	if total := atomic.AddInt64(&b.bytes, int64(n)); b.limits.Bytes > 0 && total >= b.limits.Bytes {
+		b.exhaust(BudgetBytes)
+	}
+}
+
+// spent reports whether any budget is exhausted.
+func (b *crawlBudget) spent() bool {
+	if !b.deadline.IsZero() && time.Now().After(b.deadline) {
+		b.exhaust(BudgetDuration)
+	}
+
+	b.ml.Lock()
+	defer b.ml.Unlock()
+
+	return b.exhausted != ""
+}
+
+// exhaust records the giving budget as exhausted, unless another budget was
+// exhausted first.
+func (b *crawlBudget) exhaust(budget string) {
+	b.ml.Lock()
+	defer b.ml.Unlock()
+
+	if b.exhausted == "" {
+		b.exhausted = budget
+	}
+}
+
+// skip records a link left unexplored as the budget is spent.
+func (b *crawlBudget) skip() {
+	atomic.AddInt64(&b.unexplored, 1)
+}
+
+// refused reports whether the giving error is from a request refused as the
+// budget is spent.
+func (b *crawlBudget) refused(err error) bool {
+	return errors.Is(err, errBudgetExhausted)
+}
+
+// report returns what the crawl spent of its budget.
+func (b *crawlBudget) report() *BudgetReport {
+	b.ml.Lock()
+	defer b.ml.Unlock()
+
+	return &BudgetReport{
+		Exhausted:  b.exhausted,
+		Pages:      atomic.LoadInt64(&b.pages),
+		Requests:   atomic.LoadInt64(&b.requests),
+		Bytes:      atomic.LoadInt64(&b.bytes),
+		Unexplored: atomic.LoadInt64(&b.unexplored),
+	}
+}
+
+//==============================================================================
+
+// budgetTransport counts the requests and downloaded bytes of a crawl against
+// its budget, refusing requests once it is spent. It implements the
+// http.RoundTripper interface.
+type budgetTransport struct {
+	next   http.RoundTripper
+	budget *crawlBudget
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.budget.request() {
+		return nil, errBudgetExhausted
+	}
+
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	res, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Body = &budgetBody{ReadCloser: res.Body, budget: t.budget}
+	return res, nil
+}
+
+// budgetBody counts the bytes read from a response body against the budget.
+type budgetBody struct {
+	io.ReadCloser
+	budget *crawlBudget
+}
+
+// Read implements the io.Reader interface.
+func (b *budgetBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.budget.read(n)
+	return n, err
+}
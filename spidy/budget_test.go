@@ -0,0 +1,125 @@
+package spidy_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+// TestBudget tests stopping a crawl once any of its budgets is exhausted.
+func TestBudget(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to limit what a crawl spends")
+	{
+		// Each page links to the next up to the last, so the crawl runs out
+		// of budget well before it runs out of pages.
+		const last = 100
+
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			n, _ := strconv.Atoi(strings.TrimPrefix(req.URL.Path, "/"))
+
+			time.Sleep(5 * time.Millisecond)
+
+			var next string
+			if n < last {
+				next = fmt.Sprintf(`<a href="/%d">Next</a>`, n+1)
+			}
+
+			res.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(res, `<html><body><p>Page %d</p>%s</body></html>`, n, next)
+		}))
+
+		defer server.Close()
+
+		crawl := func(budget spidy.Budget) *spidy.Report {
+			conf := spidy.Config{
+				Client:  &http.Client{Timeout: 30 * time.Second},
+				URL:     server.URL + "/0",
+				Workers: 10,
+				Depth:   -1,
+				Events:  events,
+				Budget:  budget,
+			}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+
+			return report
+		}
+
+		budgets := []struct {
+			name   string
+			budget spidy.Budget
+			check  func(b *spidy.BudgetReport) bool
+		}{
+			{spidy.BudgetPages, spidy.Budget{Pages: 3}, func(b *spidy.BudgetReport) bool { return b.Pages == 3 }},
+			{spidy.BudgetRequests, spidy.Budget{Requests: 7}, func(b *spidy.BudgetReport) bool { return b.Requests == 7 }},
+			{spidy.BudgetBytes, spidy.Budget{Bytes: 300}, func(b *spidy.BudgetReport) bool { return b.Bytes >= 300 }},
+			{spidy.BudgetDuration, spidy.Budget{Duration: 100 * time.Millisecond}, func(b *spidy.BudgetReport) bool { return b.Pages > 0 }},
+		}
+
+		for _, bt := range budgets {
+			t.Logf("\tWhen crawling a long site with a %s budget", bt.name)
+			{
+				report := crawl(bt.budget)
+				b := report.Budget
+
+				if b == nil || b.Exhausted != bt.name || !bt.check(b) {
+					t.Errorf("\t%s\tShould have stopped at the %s budget but got %+v", tests.Failed, bt.name, b)
+					continue
+				}
+				t.Logf("\t%s\tShould have stopped at the %s budget", tests.Success, bt.name)
+
+				if b.Unexplored == 0 {
+					t.Errorf("\t%s\tShould have counted the links left unexplored", tests.Failed)
+				} else {
+					t.Logf("\t%s\tShould have counted the links left unexplored", tests.Success)
+				}
+
+				var partial, failed bool
+				for _, f := range report.Links {
+					switch {
+					case f.Kind == spidy.KindBudget:
+						partial = true
+					case f.Severity == spidy.SeverityError:
+						failed = true
+					}
+				}
+
+				if !partial || failed {
+					t.Errorf("\t%s\tShould have reported the crawl as partial without failing links but got %v", tests.Failed, report.Links)
+				} else {
+					t.Logf("\t%s\tShould have reported the crawl as partial without failing links", tests.Success)
+				}
+			}
+		}
+
+		t.Logf("\tWhen crawling a site within its budget")
+		{
+			report := crawl(spidy.Budget{Pages: last + 1})
+
+			if b := report.Budget; b == nil || b.Exhausted != "" || b.Pages != last+1 || b.Unexplored != 0 {
+				t.Errorf("\t%s\tShould have crawled the whole site but got %+v", tests.Failed, b)
+			} else {
+				t.Logf("\t%s\tShould have crawled the whole site", tests.Success)
+			}
+
+			for _, f := range report.Links {
+				if f.Kind == spidy.KindBudget {
+					t.Errorf("\t%s\tShould not have reported the crawl as partial", tests.Failed)
+				}
+			}
+		}
+	}
+}
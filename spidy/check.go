@@ -0,0 +1,181 @@
+package spidy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/kit/pool"
+)
+
+// CheckLink defines a link to check, with the optional source it came from
+// such as the page or record which references it, and the line of the list it
+// was read from if any.
+type CheckLink struct {
+	URL    string
+	Source string
+	Line   int
+}
+
+// ReadLinks reads the links to check from the giving reader, one per line. A
+// link may be followed by a tab or spaces and its source, blank lines and
+// lines starting with # are skipped.
+func ReadLinks(r io.Reader) ([]CheckLink, error) {
+	var links []CheckLink
+	var n int
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		n++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		link := CheckLink{Line: n}
+
+		if i := strings.IndexAny(line, "\t "); i >= 0 {
+			link.URL = line[:i]
+			link.Source = strings.TrimSpace(line[i+1:])
+		} else {
+			link.URL = line
+		}
+
+		links = append(links, link)
+	}
+
+	return links, scanner.Err()
+}
+
+//==============================================================================
+
+// Check evaluates each of the giving links without crawling any of them,
+// returning the report of the links which failed else returns a non-nil error
+// if it failed. Links which appear more than once are only checked once but
+// reported for each of their sources. Links which are not absolute URLs are
+// reported as invalid, so a corrupted list doesn't pass unnoticed.
+func Check(context interface{}, c *Config, links []CheckLink) (*Report, error) {
+	c.Events.Event(context, "Check", "Started : Links[%d] : Workers[%d] : HTTPTimeout[%s]", len(links), c.Workers, c.Client.Timeout)
+
+	conf, audit, err := prepare(context, c)
+	if err != nil {
+		c.Events.ErrorEvent(context, "Check", err, "Completed")
+		return nil, err
+	}
+
+	report := Report{Started: time.Now().UTC()}
+
+	// Group the sources of each link so it's only checked once.
+	var order []string
+	sources := make(map[string][]string)
+
+	for _, link := range links {
+		uri, err := url.Parse(link.URL)
+		if err != nil || !uri.IsAbs() {
+			c.Events.Event(context, "Check", "Invalid Link[%s] : Line[%d]", link.URL, link.Line)
+			report.Links = append(report.Links, invalidLink(link))
+			continue
+		}
+
+		key := uri.String()
+		if _, ok := sources[key]; !ok {
+			order = append(order, key)
+		}
+
+		sources[key] = append(sources[key], link.Source)
+	}
+
+	poolCfg := pool.Config{
+		OptEvent:    pool.OptEvent{Event: c.Events.Event},
+		MinRoutines: func() int { return 10 },
+		MaxRoutines: func() int { return c.Workers },
+	}
+
+	pl, err := pool.New(context, "Check", poolCfg)
+	if err != nil {
+		c.Events.ErrorEvent(context, "Check", err, "Completed")
+		return nil, err
+	}
+
+	var ml sync.Mutex
+	var wait sync.WaitGroup
+
+	for _, link := range order {
+		wait.Add(1)
+
+		pl.Do(context, &checkBot{
+			config:  conf,
+			link:    link,
+			sources: sources[link],
+			wait:    &wait,
+			ml:      &ml,
+			report:  &report,
+		})
+	}
+
+	wait.Wait()
+	pl.Shutdown(context)
+
+	certs, warnings := audit.report(c.CertExpiry)
+	report.Certs = certs
+	report.Links = append(report.Links, warnings...)
+	report.Finished = time.Now().UTC()
+
+	report.suppress(c.Suppressions, report.Finished)
+
+	c.Events.Event(context, "Check", "Completed : Total Findings[%d] : Total Certificates[%d]", len(report.Links), len(report.Certs))
+	return &report, nil
+}
+
+// invalidLink returns the finding of the giving link which is not an absolute
+// URL, pointing at the line of the list it was read from if any.
+func invalidLink(link CheckLink) LinkReport {
+	msg := "Invalid link : Expected an absolute URL"
+	if link.Line > 0 {
+		msg = fmt.Sprintf("Invalid link on line %d : Expected an absolute URL", link.Line)
+	}
+
+	return LinkReport{
+		Link:     link.URL,
+		Source:   link.Source,
+		Error:    errors.New(msg),
+		Kind:     KindInvalidLink,
+		Severity: SeverityError,
+	}
+}
+
+//==============================================================================
+
+// checkBot provides a worker which checks a single link, reporting it for
+// each of its sources if it failed. It implements pool.Work interface.
+type checkBot struct {
+	config  *Config
+	link    string
+	sources []string
+	wait    *sync.WaitGroup
+	ml      *sync.Mutex
+	report  *Report
+}
+
+// Work performs the check of the link.
+func (cb *checkBot) Work(context interface{}, id int) {
+	defer cb.wait.Done()
+
+	status, _, err := evaluatePath(cb.link, cb.config)
+	if err == nil {
+		return
+	}
+
+	cb.ml.Lock()
+	defer cb.ml.Unlock()
+
+	for _, source := range cb.sources {
+		cb.report.Links = append(cb.report.Links, newLinkReport(cb.link, source, status, err))
+	}
+}
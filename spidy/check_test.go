@@ -0,0 +1,133 @@
+package spidy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// TestCheck tests checking a list of links without crawling them.
+func TestCheck(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to check a list of links")
+	{
+		var deadChecks, pageChecks int64
+
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/page":
+				atomic.AddInt64(&pageChecks, 1)
+				res.Header().Set("Content-Type", "text/html")
+				res.Write([]byte(`<html><body><a href="/linked-dead">Dead</a></body></html>`))
+			case "/dead":
+				atomic.AddInt64(&deadChecks, 1)
+				res.WriteHeader(http.StatusNotFound)
+			default:
+				res.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		defer server.Close()
+
+		list := `# Links exported from the CMS
+` + server.URL + `/page	article-1
+` + server.URL + `/dead	article-1
+
+` + server.URL + `/dead   article 2
+/relative
+`
+
+		t.Logf("\tWhen reading the list of links")
+		{
+			links, err := spidy.ReadLinks(strings.NewReader(list))
+			if err != nil {
+				t.Fatalf("\t%s\tShould have read the links: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have read the links", tests.Success)
+
+			if len(links) != 4 {
+				t.Fatalf("\t%s\tShould have read 4 links but got %d", tests.Failed, len(links))
+			}
+			t.Logf("\t%s\tShould have read 4 links", tests.Success)
+
+			if links[2].Source != "article 2" {
+				t.Errorf("\t%s\tShould have read the source column but got %q", tests.Failed, links[2].Source)
+			} else {
+				t.Logf("\t%s\tShould have read the source column", tests.Success)
+			}
+
+			conf := spidy.Config{
+				Client:  &http.Client{Timeout: 30 * time.Second},
+				Workers: 10,
+				Events:  events,
+			}
+
+			report, err := spidy.Check(context, &conf, links)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have checked the links: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have checked the links", tests.Success)
+
+			if checks := atomic.LoadInt64(&deadChecks); checks != 1 {
+				t.Errorf("\t%s\tShould have checked the duplicated link once but got %d", tests.Failed, checks)
+			} else {
+				t.Logf("\t%s\tShould have checked the duplicated link once", tests.Success)
+			}
+
+			if checks := atomic.LoadInt64(&pageChecks); checks != 1 {
+				t.Errorf("\t%s\tShould have only checked the page, without crawling it, but got %d", tests.Failed, checks)
+			} else {
+				t.Logf("\t%s\tShould have only checked the page, without crawling it", tests.Success)
+			}
+
+			var dead []spidy.LinkReport
+			var invalid []spidy.LinkReport
+
+			for _, f := range report.Links {
+				if f.Kind == spidy.KindInvalidLink {
+					invalid = append(invalid, f)
+					continue
+				}
+
+				dead = append(dead, f)
+			}
+
+			if len(invalid) != 1 || invalid[0].Link != "/relative" || invalid[0].Severity != spidy.SeverityError || !strings.Contains(invalid[0].Error.Error(), "line 6") {
+				t.Errorf("\t%s\tShould have reported the invalid link with its line: %+v", tests.Failed, invalid)
+			} else {
+				t.Logf("\t%s\tShould have reported the invalid link with its line", tests.Success)
+			}
+
+			if len(dead) != 2 {
+				t.Fatalf("\t%s\tShould have reported the dead link for each source but got %d findings", tests.Failed, len(dead))
+			}
+			t.Logf("\t%s\tShould have reported the dead link for each source", tests.Success)
+
+			for _, f := range dead {
+				if strings.Contains(f.Link, "linked-dead") {
+					t.Errorf("\t%s\tShould not have followed the links of the page", tests.Failed)
+				}
+
+				if f.Status != http.StatusNotFound || f.Severity != spidy.SeverityError {
+					t.Errorf("\t%s\tShould have reported a 404 error for %s but got %d %s", tests.Failed, f.Source, f.Status, f.Severity)
+				}
+			}
+
+			if !report.Failed() {
+				t.Errorf("\t%s\tShould have failed the report", tests.Failed)
+			} else {
+				t.Logf("\t%s\tShould have failed the report", tests.Success)
+			}
+		}
+	}
+}
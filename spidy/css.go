@@ -0,0 +1,84 @@
+package spidy
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// cssComment matches comments within stylesheets.
+	cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+	// cssImport matches @import rules, with either a string or url(...).
+	cssImport = regexp.MustCompile(`(?i)@import\s+(?:"([^"]*)"|'([^']*)'|url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"\s]*))\s*\))`)
+
+	// cssURL matches url(...) references, quoted or unquoted.
+	cssURL = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"\s]*))\s*\)`)
+)
+
+// cssRef defines a reference found within a stylesheet.
+type cssRef struct {
+	URL    string
+	Import bool
+}
+
+// cssRefs returns the @import and url(...) references within the giving
+// stylesheet text, skipping inline data URIs.
+func cssRefs(text string) []cssRef {
+	text = cssComment.ReplaceAllString(text, "")
+
+	var refs []cssRef
+
+	// Only one of the alternative groups of a match is ever set.
+	add := func(match []string, imported bool) {
+		for _, value := range match[1:] {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+
+			if !strings.HasPrefix(strings.ToLower(value), "data:") {
+				refs = append(refs, cssRef{URL: value, Import: imported})
+			}
+			return
+		}
+	}
+
+	for _, match := range cssImport.FindAllStringSubmatch(text, -1) {
+		add(match, true)
+	}
+
+	// Imports are removed so their url(...) isn't matched twice.
+	text = cssImport.ReplaceAllString(text, "")
+
+	for _, match := range cssURL.FindAllStringSubmatch(text, -1) {
+		add(match, false)
+	}
+
+	return refs
+}
+
+// cssLinks returns the pageLinks for the references within the giving
+// stylesheet text, found within the element and attribute given.
+func cssLinks(text string, element string, attr string) []pageLink {
+	var links []pageLink
+
+	for _, ref := range cssRefs(text) {
+		link := pageLink{URL: ref.URL, Element: element, Attr: attr, Reference: "stylesheet url"}
+
+		if ref.Import {
+			link.Attr = "@import"
+			link.Reference = "stylesheet import"
+		}
+
+		links = append(links, link)
+	}
+
+	return links
+}
+
+// isStylesheet reports whether the giving content type is that of a
+// stylesheet.
+func isStylesheet(contentType string) bool {
+	return strings.Contains(contentType, "text/css")
+}
@@ -0,0 +1,110 @@
+package spidy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// stylesPage provides a page referencing resources through stylesheets.
+var stylesPage = []byte(`
+<!DOCTYPE html>
+<html>
+<head>
+	<link rel="stylesheet" href="/css/site.css">
+	<style>
+		/* background: url(/img/commented.png); */
+		body { background: url("/img/body.png"); }
+		.logo { background: url(data:image/png;base64,iVBORw0KGgo=); }
+	</style>
+</head>
+<body>
+	<div style="background-image: url('/img/missing-div.png')"></div>
+</body>
+</html>`)
+
+// stylesSheet provides a stylesheet referencing fonts, images and imports
+// relative to itself.
+var stylesSheet = []byte(`
+@import "print.css";
+@import url("missing-import.css");
+
+@font-face { src: url(../fonts/site.woff) format("woff"), url('../fonts/missing.woff'); }
+.header { background: url(img/header.png); }
+`)
+
+// TestStylesheets tests the harvesting of links from stylesheets, <style>
+// blocks and style attributes.
+func TestStylesheets(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to check the resources referenced by stylesheets")
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/":
+				res.Write(stylesPage)
+			case "/css/site.css":
+				res.Header().Set("Content-Type", "text/css")
+				res.Write(stylesSheet)
+			case "/css/print.css":
+				res.Header().Set("Content-Type", "text/css")
+			case "/img/body.png", "/fonts/site.woff", "/css/img/header.png":
+				res.Header().Set("Content-Type", "application/octet-stream")
+			default:
+				res.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		defer server.Close()
+
+		conf := spidy.Config{
+			Client:  &http.Client{Timeout: 30 * time.Second},
+			URL:     server.URL,
+			Workers: 30,
+			Depth:   -1,
+			Events:  events,
+		}
+
+		t.Logf("\tWhen crawling a page with stylesheets")
+		{
+			badlinks, err := spidy.Run(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have successfully retrieved page[%s]: %q", tests.Failed, conf.URL, err)
+			}
+			t.Logf("\t%s\tShould have successfully retrieved page[%s]", tests.Success, conf.URL)
+
+			var links []string
+			for _, report := range badlinks {
+				links = append(links, report.Link)
+			}
+
+			sort.Strings(links)
+
+			expected := []string{
+				server.URL + "/css/missing-import.css",
+				server.URL + "/fonts/missing.woff",
+				server.URL + "/img/missing-div.png",
+			}
+
+			if len(links) != len(expected) {
+				t.Fatalf("\t%s\tShould have found %d dead stylesheet links: %+v", tests.Failed, len(expected), badlinks)
+			}
+
+			for i := range expected {
+				if links[i] != expected[i] {
+					t.Fatalf("\t%s\tShould have found dead link[%s]: %+v", tests.Failed, expected[i], badlinks)
+				}
+			}
+			t.Logf("\t%s\tShould have found %d dead stylesheet links", tests.Success, len(expected))
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package spidy
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Changes of a finding between two reports.
+const (
+	ChangeBroken = "broken" // Findings only within the newer report.
+	ChangeFixed  = "fixed"  // Findings only within the older report.
+	ChangeStill  = "still"  // Findings within both reports.
+)
+
+// DiffReport defines the changes of the findings between two reports.
+type DiffReport struct {
+	OldStarted time.Time    `json:"oldStarted"`
+	NewStarted time.Time    `json:"newStarted"`
+	Broken     []LinkReport `json:"broken"`
+	Fixed      []LinkReport `json:"fixed"`
+	Still      []LinkReport `json:"stillBroken"`
+}
+
+// Regressed reports whether the newer report holds findings with an error
+// severity which the older report did not.
+func (d *DiffReport) Regressed() bool {
+	for _, link := range d.Broken {
+		if link.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Changed reports whether any finding is newly broken or fixed.
+func (d *DiffReport) Changed() bool {
+	return len(d.Broken) > 0 || len(d.Fixed) > 0
+}
+
+// Diff classifies the findings of two reports of the same site as newly
+// broken, fixed or still broken. Findings are matched by the canonical form
+// of their URL and referrer as well as their kind, so a link which is found
+// by several pages is tracked for each of them.
+func Diff(older *Report, newer *Report) *DiffReport {
+	diff := DiffReport{
+		OldStarted: older.Started,
+		NewStarted: newer.Started,
+	}
+
+	olds := make(map[string]bool)
+	for _, link := range older.Links {
+		olds[findingKey(link)] = true
+	}
+
+	news := make(map[string]bool)
+	for _, link := range newer.Links {
+		key := findingKey(link)
+		if news[key] {
+			continue
+		}
+
+		news[key] = true
+
+		if olds[key] {
+			diff.Still = append(diff.Still, link)
+			continue
+		}
+
+		diff.Broken = append(diff.Broken, link)
+	}
+
+	fixed := make(map[string]bool)
+	for _, link := range older.Links {
+		key := findingKey(link)
+		if news[key] || fixed[key] {
+			continue
+		}
+
+		fixed[key] = true
+		diff.Fixed = append(diff.Fixed, link)
+	}
+
+	return &diff
+}
+
+// findingKey returns the key which identifies a finding across reports.
+func findingKey(l LinkReport) string {
+	return canonicalURL(l.Link) + " " + canonicalURL(l.Source) + " " + l.Kind
+}
+
+// canonicalURL returns the canonical form of the giving URL, with its scheme
+// and host lowercased and any default port, fragment and empty path dropped.
+// URLs which fail to parse are returned as is.
+func canonicalURL(link string) string {
+	uri, err := url.Parse(link)
+	if err != nil || !uri.IsAbs() {
+		return link
+	}
+
+	uri.Scheme = strings.ToLower(uri.Scheme)
+	uri.Host = strings.ToLower(uri.Host)
+	uri.Fragment = ""
+	uri.RawFragment = ""
+
+	switch {
+	case uri.Scheme == "http" && strings.HasSuffix(uri.Host, ":80"):
+		uri.Host = strings.TrimSuffix(uri.Host, ":80")
+	case uri.Scheme == "https" && strings.HasSuffix(uri.Host, ":443"):
+		uri.Host = strings.TrimSuffix(uri.Host, ":443")
+	}
+
+	if uri.Path == "" {
+		uri.Path = "/"
+	}
+
+	return uri.String()
+}
+
+//==============================================================================
+
+// WriteDiff writes the giving diff to w in the giving format.
+func WriteDiff(w io.Writer, d *DiffReport, format string) error {
+	switch format {
+	case "", FormatText:
+		return writeDiffText(w, d)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	case FormatCSV:
+		return writeDiffCSV(w, d)
+	}
+
+	return fmt.Errorf("Invalid report format[%s]", format)
+}
+
+// writeDiffText writes the diff in a human readable form, with the findings
+// grouped by their change.
+func writeDiffText(w io.Writer, d *DiffReport) error {
+	fmt.Fprintln(w, "--------------------Diff------------------------------------")
+	fmt.Fprintf(w, `
+Old Run: %s
+New Run: %s
+Newly Broken: %d
+Fixed: %d
+Still Broken: %d
+`, d.OldStarted, d.NewStarted, len(d.Broken), len(d.Fixed), len(d.Still))
+
+	sections := []struct {
+		title string
+		links []LinkReport
+	}{
+		{"--------------------NEWLY BROKEN----------------------------", d.Broken},
+		{"--------------------FIXED-----------------------------------", d.Fixed},
+		{"--------------------STILL BROKEN----------------------------", d.Still},
+	}
+
+	for _, section := range sections {
+		if len(section.links) == 0 {
+			continue
+		}
+
+		fmt.Fprintln(w, section.title)
+
+		for _, f := range section.links {
+			writeFinding(w, f)
+		}
+	}
+
+	_, err := fmt.Fprintln(w, "------------------------------------------------------------")
+	return err
+}
+
+// writeDiffCSV writes the findings of the diff as CSV, one finding per row
+// with its change in the first column.
+func writeDiffCSV(w io.Writer, d *DiffReport) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(append([]string{"change"}, csvHeader...)); err != nil {
+		return err
+	}
+
+	changes := []struct {
+		change string
+		links  []LinkReport
+	}{
+		{ChangeBroken, d.Broken},
+		{ChangeFixed, d.Fixed},
+		{ChangeStill, d.Still},
+	}
+
+	for _, c := range changes {
+		for _, f := range c.links {
+			if err := cw.Write(append([]string{c.change}, csvRow(f)...)); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
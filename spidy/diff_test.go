@@ -0,0 +1,158 @@
+package spidy_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// TestDiff tests classifying the changes of findings between two runs.
+func TestDiff(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	finding := func(link, source string, severity spidy.Severity) spidy.LinkReport {
+		return spidy.LinkReport{
+			Link:     link,
+			Source:   source,
+			Status:   404,
+			Error:    errors.New("Link Failed"),
+			Kind:     spidy.KindDeadLink,
+			Severity: severity,
+		}
+	}
+
+	older := spidy.Report{
+		Links: []spidy.LinkReport{
+			finding("HTTP://Example.com:80/still#top", "http://example.com/", spidy.SeverityError),
+			finding("http://example.com/fixed", "http://example.com/", spidy.SeverityError),
+			finding("http://example.com/moved", "http://example.com/a", spidy.SeverityError),
+		},
+	}
+
+	t.Logf("Given the need to compare the reports of two runs")
+	{
+		t.Logf("\tWhen comparing reports read back from JSON and CSV")
+		{
+			newer := spidy.Report{
+				Links: []spidy.LinkReport{
+					finding("http://example.com/still", "http://example.com", spidy.SeverityError),
+					finding("http://example.com/moved", "http://example.com/b", spidy.SeverityError),
+				},
+			}
+
+			var oldJSON, newCSV bytes.Buffer
+
+			if err := spidy.WriteReport(&oldJSON, &older, spidy.FormatJSON); err != nil {
+				t.Fatalf("\t%s\tShould be able to write the old report: %q", tests.Failed, err)
+			}
+
+			if err := spidy.WriteReport(&newCSV, &newer, spidy.FormatCSV); err != nil {
+				t.Fatalf("\t%s\tShould be able to write the new report: %q", tests.Failed, err)
+			}
+
+			oldReport, err := spidy.ReadReport(&oldJSON, spidy.FormatJSON)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have read the JSON report: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have read the JSON report", tests.Success)
+
+			newReport, err := spidy.ReadReport(&newCSV, spidy.FormatCSV)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have read the CSV report: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have read the CSV report", tests.Success)
+
+			diff := spidy.Diff(oldReport, newReport)
+
+			if len(diff.Still) != 1 || diff.Still[0].Link != "http://example.com/still" {
+				t.Errorf("\t%s\tShould have matched the still broken link by its canonical URL: %v", tests.Failed, diff.Still)
+			} else {
+				t.Logf("\t%s\tShould have matched the still broken link by its canonical URL", tests.Success)
+			}
+
+			if len(diff.Fixed) != 2 {
+				t.Errorf("\t%s\tShould have found 2 fixed links but got %d", tests.Failed, len(diff.Fixed))
+			} else {
+				t.Logf("\t%s\tShould have found 2 fixed links", tests.Success)
+			}
+
+			if len(diff.Broken) != 1 || diff.Broken[0].Source != "http://example.com/b" {
+				t.Errorf("\t%s\tShould have found the link broken on a new referrer: %v", tests.Failed, diff.Broken)
+			} else {
+				t.Logf("\t%s\tShould have found the link broken on a new referrer", tests.Success)
+			}
+
+			if !diff.Regressed() {
+				t.Errorf("\t%s\tShould have regressed", tests.Failed)
+			} else {
+				t.Logf("\t%s\tShould have regressed", tests.Success)
+			}
+
+			var out bytes.Buffer
+			if err := spidy.WriteDiff(&out, diff, spidy.FormatCSV); err != nil {
+				t.Fatalf("\t%s\tShould be able to write the diff: %q", tests.Failed, err)
+			}
+
+			if !strings.Contains(out.String(), "broken,,http://example.com/moved,http://example.com/b") {
+				t.Errorf("\t%s\tShould have written the change of each finding: %s", tests.Failed, out.String())
+			} else {
+				t.Logf("\t%s\tShould have written the change of each finding", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen comparing a report read back from text")
+		{
+			var text bytes.Buffer
+			if err := spidy.WriteReport(&text, &older, spidy.FormatText); err != nil {
+				t.Fatalf("\t%s\tShould be able to write the report: %q", tests.Failed, err)
+			}
+
+			// Text reports are told apart by their content, whatever the
+			// format they are read as.
+			for _, format := range []string{spidy.FormatText, spidy.FormatJSON} {
+				report, err := spidy.ReadReport(bytes.NewReader(text.Bytes()), format)
+				if err != nil {
+					t.Fatalf("\t%s\tShould have read the text report as %s: %q", tests.Failed, format, err)
+				}
+
+				diff := spidy.Diff(&older, report)
+
+				switch {
+				case len(report.Links) != len(older.Links):
+					t.Errorf("\t%s\tShould have read every finding of the text report as %s: %+v", tests.Failed, format, report.Links)
+				case report.Links[0].Status != 404 || report.Links[0].Kind != spidy.KindDeadLink || report.Links[0].Error == nil:
+					t.Errorf("\t%s\tShould have read the fields of the findings of the text report as %s: %+v", tests.Failed, format, report.Links[0])
+				case diff.Changed() || len(diff.Still) != len(older.Links):
+					t.Errorf("\t%s\tShould have found the findings of the text report as %s still broken: %+v", tests.Failed, format, diff)
+				default:
+					t.Logf("\t%s\tShould have compared the text report read as %s", tests.Success, format)
+				}
+			}
+		}
+
+		t.Logf("\tWhen comparing reports with only pre-existing and new warnings")
+		{
+			newer := spidy.Report{
+				Links: []spidy.LinkReport{
+					finding("http://example.com/still", "http://example.com/", spidy.SeverityError),
+					finding("http://example.com/slow", "http://example.com/", spidy.SeverityWarning),
+				},
+			}
+
+			diff := spidy.Diff(&older, &newer)
+
+			if diff.Regressed() {
+				t.Errorf("\t%s\tShould not have regressed", tests.Failed)
+			} else {
+				t.Logf("\t%s\tShould not have regressed", tests.Success)
+			}
+		}
+	}
+}
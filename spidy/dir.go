@@ -0,0 +1,169 @@
+package spidy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// dirTransport provides a http.RoundTripper which serves the pages of a site
+// under a base URL from the files of a local directory, such as the output
+// of a static site generator, passing any other request to the next
+// transport.
+type dirTransport struct {
+	dir  string
+	base *url.URL
+	next http.RoundTripper
+}
+
+// newDirTransport returns a new dirTransport serving the giving directory for
+// the base URL.
+func newDirTransport(dir string, base string, next http.RoundTripper) (*dirTransport, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("Invalid site directory[%s] : Not a directory", dir)
+	}
+
+	baseURI, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+
+	if !baseURI.IsAbs() {
+		return nil, fmt.Errorf("Invalid base URL[%s] : Not an absolute URL", base)
+	}
+
+	if !strings.HasSuffix(baseURI.Path, "/") {
+		baseURI.Path += "/"
+	}
+
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &dirTransport{dir: dir, base: baseURI, next: next}, nil
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (d *dirTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.EqualFold(req.URL.Scheme, d.base.Scheme) || !strings.EqualFold(req.URL.Host, d.base.Host) {
+		return d.next.RoundTrip(req)
+	}
+
+	if req.URL.Path+"/" == d.base.Path {
+		return d.redirect(req, d.base.Path), nil
+	}
+
+	if !strings.HasPrefix(req.URL.Path, d.base.Path) {
+		return d.next.RoundTrip(req)
+	}
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return d.respond(req, http.StatusMethodNotAllowed), nil
+	}
+
+	// Cleaning a rooted path drops any .. elements which climb above it.
+	name := path.Clean("/" + strings.TrimPrefix(req.URL.Path, d.base.Path))
+	file := filepath.Join(d.dir, filepath.FromSlash(name))
+
+	info, err := os.Stat(file)
+	switch {
+	case err == nil && info.IsDir():
+
+		// Directories are served by their index, redirecting to the path with
+		// a trailing slash first as web servers do so relative links resolve
+		// against the directory.
+		if !strings.HasSuffix(req.URL.Path, "/") {
+			return d.redirect(req, req.URL.Path+"/"), nil
+		}
+
+		return d.serve(req, filepath.Join(file, "index.html"))
+
+	case err == nil:
+		return d.serve(req, file)
+
+	case !strings.HasSuffix(req.URL.Path, "/") && path.Ext(name) == "":
+
+		// Pretty URLs such as /about are served by about.html.
+		return d.serve(req, file+".html")
+	}
+
+	return d.respond(req, http.StatusNotFound), nil
+}
+
+// serve returns the response serving the giving file, or a 404 response if
+// the file does not exist.
+func (d *dirTransport) serve(req *http.Request, file string) (*http.Response, error) {
+	info, err := os.Stat(file)
+	if err != nil || info.IsDir() {
+		return d.respond(req, http.StatusNotFound), nil
+	}
+
+	res := d.respond(req, http.StatusOK)
+	res.ContentLength = info.Size()
+	res.Header.Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+
+	fd, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(file))
+	if contentType == "" {
+		sniff := make([]byte, 512)
+		n, _ := fd.Read(sniff)
+		contentType = http.DetectContentType(sniff[:n])
+
+		if _, err := fd.Seek(0, 0); err != nil {
+			fd.Close()
+			return nil, err
+		}
+	}
+
+	res.Header.Set("Content-Type", contentType)
+
+	if req.Method == http.MethodHead {
+		fd.Close()
+		return res, nil
+	}
+
+	res.Body = fd
+	return res, nil
+}
+
+// redirect returns a response redirecting the request to the giving path.
+func (d *dirTransport) redirect(req *http.Request, location string) *http.Response {
+	res := d.respond(req, http.StatusMovedPermanently)
+
+	target := *req.URL
+	target.Path = location
+	target.RawPath = ""
+	res.Header.Set("Location", target.String())
+
+	return res
+}
+
+// respond returns an empty response with the giving status for the request.
+func (d *dirTransport) respond(req *http.Request, status int) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+}
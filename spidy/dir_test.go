@@ -0,0 +1,124 @@
+package spidy_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// TestDir tests crawling a static site from a local directory.
+func TestDir(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to crawl a static site from disk")
+	{
+		external := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != "/ok" {
+				res.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		defer external.Close()
+
+		dir, err := ioutil.TempDir("", "spidy-site")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a site directory: %q", tests.Failed, err)
+		}
+		defer os.RemoveAll(dir)
+
+		files := map[string]string{
+			"index.html": fmt.Sprintf(`<html><head><link rel="stylesheet" href="/style.css"></head><body>
+				<a href="/about">About</a>
+				<a href="/docs">Docs</a>
+				<a href="/missing">Missing</a>
+				<a href="%s/ok">External</a>
+				<a href="%s/gone">Gone</a>
+			</body></html>`, external.URL, external.URL),
+			"about.html":            `<html><body><a href="/">Home</a></body></html>`,
+			"docs/index.html":       `<html><body><a href="intro/">Intro</a><a href="setup">Setup</a></body></html>`,
+			"docs/intro/index.html": `<html><body><a href="../../about">About</a></body></html>`,
+			"style.css":             `body { background: url(/images/missing.png); }`,
+		}
+
+		for name, content := range files {
+			file := filepath.Join(dir, filepath.FromSlash(name))
+			os.MkdirAll(filepath.Dir(file), 0755)
+
+			if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+				t.Fatalf("\t%s\tShould be able to write %s: %q", tests.Failed, name, err)
+			}
+		}
+
+		t.Logf("\tWhen crawling the directory under a base URL")
+		{
+			conf := spidy.Config{
+				Client:  &http.Client{Timeout: 30 * time.Second},
+				All:     true,
+				Workers: 10,
+				Depth:   -1,
+				Events:  events,
+				Dir:     dir,
+				Base:    "http://docs.example.com",
+			}
+
+			conf.URL = conf.Base
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the directory: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have crawled the directory", tests.Success)
+
+			var dead []string
+			for _, f := range report.Links {
+				dead = append(dead, f.Link)
+			}
+
+			sort.Strings(dead)
+
+			expected := []string{
+				"http://docs.example.com/docs/setup",
+				"http://docs.example.com/images/missing.png",
+				"http://docs.example.com/missing",
+				external.URL + "/gone",
+			}
+
+			sort.Strings(expected)
+
+			if fmt.Sprint(dead) != fmt.Sprint(expected) {
+				t.Fatalf("\t%s\tShould have found the dead links %v but got %v", tests.Failed, expected, dead)
+			}
+			t.Logf("\t%s\tShould have resolved index and pretty URLs and checked external links over HTTP", tests.Success)
+		}
+
+		t.Logf("\tWhen crawling a directory which does not exist")
+		{
+			conf := spidy.Config{
+				Client:  &http.Client{Timeout: 30 * time.Second},
+				URL:     "http://docs.example.com",
+				Workers: 10,
+				Depth:   -1,
+				Events:  events,
+				Dir:     filepath.Join(dir, "none"),
+			}
+
+			if _, err := spidy.Crawl(context, &conf); err == nil {
+				t.Errorf("\t%s\tShould have failed to crawl", tests.Failed)
+			} else {
+				t.Logf("\t%s\tShould have failed to crawl", tests.Success)
+			}
+		}
+	}
+}
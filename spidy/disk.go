@@ -0,0 +1,246 @@
+package spidy
+
+import (
+	"bufio"
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"os"
+	"sync"
+)
+
+// Defaults of a DiskVisited.
+const (
+	DefaultVisitedCapacity      = 10000000
+	DefaultVisitedFalsePositive = 0.000001
+)
+
+// DiskFrontier holds the pending pages of a crawl in a file, in the order they
+// were pushed, so only a buffer of them is held in memory. It implements the
+// Frontier interface.
+type DiskFrontier struct {
+	path string
+
+	ml     sync.Mutex
+	file   *os.File
+	rfile  *os.File
+	writer *bufio.Writer
+	reader *bufio.Reader
+	pushed int
+	popped int
+}
+
+// NewDiskFrontier returns a new DiskFrontier held in the file at the giving
+// path, which is truncated if it exists.
+func NewDiskFrontier(path string) (*DiskFrontier, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries are read through their own handle, so reads and writes keep
+	// their own offsets.
+	rfile, err := os.Open(path)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	f := DiskFrontier{
+		path:   path,
+		file:   file,
+		rfile:  rfile,
+		writer: bufio.NewWriter(file),
+		reader: bufio.NewReader(rfile),
+	}
+
+	return &f, nil
+}
+
+// Push implements the Frontier interface.
+func (f *DiskFrontier) Push(entry FrontierEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f.ml.Lock()
+	defer f.ml.Unlock()
+
+	if _, err := f.writer.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	f.pushed++
+	return nil
+}
+
+// Pop implements the Frontier interface. Entries which can't be read back
+// are dropped.
+func (f *DiskFrontier) Pop() (FrontierEntry, bool) {
+	f.ml.Lock()
+	defer f.ml.Unlock()
+
+	for f.popped < f.pushed {
+		if err := f.writer.Flush(); err != nil {
+			return FrontierEntry{}, false
+		}
+
+		line, err := f.reader.ReadBytes('\n')
+		if err != nil {
+			return FrontierEntry{}, false
+		}
+
+		f.popped++
+
+		var entry FrontierEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue
+		}
+
+		return entry, true
+	}
+
+	return FrontierEntry{}, false
+}
+
+// Len implements the Frontier interface.
+func (f *DiskFrontier) Len() int {
+	f.ml.Lock()
+	defer f.ml.Unlock()
+
+	return f.pushed - f.popped
+}
+
+// Close closes and removes the file of the frontier.
+func (f *DiskFrontier) Close() error {
+	f.ml.Lock()
+	defer f.ml.Unlock()
+
+	f.file.Close()
+	f.rfile.Close()
+
+	return os.Remove(f.path)
+}
+
+//==============================================================================
+
+// DiskVisited holds the visited links of a crawl in a bloom filter of a fixed
+// size, logging every link to a file from which the filter is rebuilt when
+// the set is opened again. Memory stays bounded however many links are
+// visited, at the cost of a link being taken as visited when it was not with
+// the false positive probability the set was sized for. It implements the
+// VisitedSet interface.
+type DiskVisited struct {
+	ml     sync.Mutex
+	bits   []uint64
+	size   uint64
+	hashes int
+	file   *os.File
+	log    *bufio.Writer
+	err    error
+}
+
+// NewDiskVisited returns a new DiskVisited logging to the file at the giving
+// path, sized for the giving number of links with the giving false positive
+// probability. Links already logged to the file are visited.
+func NewDiskVisited(path string, capacity int, falsePositive float64) (*DiskVisited, error) {
+	if capacity <= 0 {
+		capacity = DefaultVisitedCapacity
+	}
+
+	if falsePositive <= 0 || falsePositive >= 1 {
+		falsePositive = DefaultVisitedFalsePositive
+	}
+
+	// The filter has the optimal number of bits and hashes for its capacity
+	// and false positive probability.
+	size := uint64(math.Ceil(-float64(capacity) * math.Log(falsePositive) / (math.Ln2 * math.Ln2)))
+	hashes := int(math.Ceil(float64(size) / float64(capacity) * math.Ln2))
+
+	v := DiskVisited{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		v.add(scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	v.file = file
+	v.log = bufio.NewWriter(file)
+
+	return &v, nil
+}
+
+// Visit implements the VisitedSet interface.
+func (v *DiskVisited) Visit(link string) bool {
+	v.ml.Lock()
+	defer v.ml.Unlock()
+
+	if !v.add(link) {
+		return true
+	}
+
+	if _, err := v.log.WriteString(link + "\n"); err != nil && v.err == nil {
+		v.err = err
+	}
+
+	return false
+}
+
+// add sets the bits of the giving link within the filter, reporting whether
+// any of them was not set.
+func (v *DiskVisited) add(link string) bool {
+	h1 := fnv.New64a()
+	h1.Write([]byte(link))
+	a := h1.Sum64()
+
+	h2 := fnv.New64()
+	h2.Write([]byte(link))
+	b := h2.Sum64() | 1
+
+	var added bool
+	for i := 0; i < v.hashes; i++ {
+		bit := (a + uint64(i)*b) % v.size
+		word, mask := bit/64, uint64(1)<<(bit%64)
+
+		if v.bits[word]&mask == 0 {
+			v.bits[word] |= mask
+			added = true
+		}
+	}
+
+	return added
+}
+
+// Close flushes and closes the log of the set, returning the first error
+// logging a link failed with, if any.
+func (v *DiskVisited) Close() error {
+	v.ml.Lock()
+	defer v.ml.Unlock()
+
+	if err := v.log.Flush(); err != nil && v.err == nil {
+		v.err = err
+	}
+
+	if err := v.file.Close(); err != nil && v.err == nil {
+		v.err = err
+	}
+
+	return v.err
+}
@@ -0,0 +1,467 @@
+package spidy
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// Kinds of duplicate content clusters.
+const (
+	DuplicateExact = "exact"
+	DuplicateNear  = "near"
+)
+
+// defaultDuplicateThreshold is the similarity at which pages are near
+// duplicates.
+const defaultDuplicateThreshold = 0.9
+
+// signatureBands is the number of bands of minhashes fingerprinting a page.
+// Only pages sharing a whole band are compared, so pages with few shingles in
+// common are rarely compared at all.
+const signatureBands = 8
+
+// maxSignatureRows is the most minhashes within a band of a signature. The
+// rows of a band are as many as keep pages as similar as the threshold
+// sharing a band half the time, so they share one of all the bands 99% of
+// the time.
+const maxSignatureRows = 8
+
+// sketchSize is the number of the lowest hashes of the shingles of a page kept
+// to estimate its similarity with others, which is exact for pages with no
+// more shingles.
+const sketchSize = 128
+
+// Duplicates configures the detection of pages published with the same or
+// nearly the same content under several URLs.
+type Duplicates struct {
+
+	// Threshold is the similarity, from 0 to 1, of the text of two pages at
+	// which they are near duplicates, defaults to 0.9.
+	Threshold float64
+}
+
+// DuplicateCluster defines a set of pages with duplicate content.
+type DuplicateCluster struct {
+	Kind       string          `json:"kind"`
+	Similarity float64         `json:"similarity"`
+	Pages      []DuplicatePage `json:"pages"`
+
+	// Canonical is the URL every page of the cluster declares as canonical,
+	// empty if they do not declare a consistent one.
+	Canonical string `json:"canonical,omitempty"`
+}
+
+// DuplicatePage defines a page within a DuplicateCluster and the canonical
+// URL it declares, if any.
+type DuplicatePage struct {
+	URL       string `json:"url"`
+	Canonical string `json:"canonical,omitempty"`
+}
+
+//==============================================================================
+
+// duplicateInspector fingerprints the text of the pages of a crawl and
+// clusters those with duplicate content once the crawl finishes. It
+// implements the SiteInspector interface.
+type duplicateInspector struct {
+	threshold float64
+	rows      int
+	events    Events
+
+	ml       sync.Mutex
+	pages    map[string]*fingerprint
+	clusters []DuplicateCluster
+}
+
+// fingerprint defines the fingerprints of the text of a page, whose size is
+// bounded whatever the length of the text.
+type fingerprint struct {
+	page      string
+	canonical string
+	hash      [sha256.Size]byte
+	bands     [signatureBands]uint64
+	sketch    []uint64
+}
+
+// newDuplicateInspector returns a new duplicateInspector for the config, or
+// nil if duplicate content is not detected.
+func newDuplicateInspector(c *Config) *duplicateInspector {
+	if c.Duplicates == nil {
+		return nil
+	}
+
+	di := duplicateInspector{
+		threshold: c.Duplicates.Threshold,
+		rows:      maxSignatureRows,
+		events:    c.Events,
+		pages:     make(map[string]*fingerprint),
+	}
+
+	if di.threshold <= 0 {
+		di.threshold = defaultDuplicateThreshold
+	}
+
+	if di.threshold < 1 {
+		rows := int(math.Log(0.5) / math.Log(di.threshold))
+		if rows < 1 {
+			rows = 1
+		}
+
+		if rows < di.rows {
+			di.rows = rows
+		}
+	}
+
+	return &di
+}
+
+// Inspect implements the PageInspector interface, recording the fingerprints
+// of the text of the page.
+func (di *duplicateInspector) Inspect(context interface{}, pg *Page) []LinkReport {
+	page := pg.URL.String()
+
+	// Links which redirect to a page already fingerprinted are the same page.
+	di.ml.Lock()
+	_, seen := di.pages[page]
+	di.ml.Unlock()
+
+	if seen {
+		return nil
+	}
+
+	text := strings.ToLower(collapseSpace(pageText(pg.Document.Nodes...)))
+	if text == "" {
+		return nil
+	}
+
+	set := shingles(text)
+
+	fp := fingerprint{
+		page:   page,
+		hash:   sha256.Sum256([]byte(text)),
+		bands:  signature(set, di.rows),
+		sketch: sketch(set),
+	}
+
+	if href, ok := pg.Document.Find("link[rel~=canonical][href]").First().Attr("href"); ok {
+		if uri, err := parsePath(strings.TrimSpace(href), documentBase(pg.Document, pg.URL)); err == nil {
+			uri.Fragment = ""
+			fp.canonical = uri.String()
+		}
+	}
+
+	di.ml.Lock()
+	di.pages[page] = &fp
+	di.ml.Unlock()
+
+	return nil
+}
+
+// Finish implements the SiteInspector interface, clustering the pages with
+// duplicate content and reporting every page of a cluster which does not
+// declare a consistent canonical URL.
+func (di *duplicateInspector) Finish(context interface{}) []LinkReport {
+	di.ml.Lock()
+	defer di.ml.Unlock()
+
+	var pages []*fingerprint
+	for _, fp := range di.pages {
+		pages = append(pages, fp)
+	}
+
+	sort.Sort(fingerprintsByPage(pages))
+
+	// Pages are joined into clusters through every pair which is identical
+	// or similar enough, each cluster keeping the lowest similarity joining
+	// its pages.
+	parent := make([]int, len(pages))
+	similarity := make([]float64, len(pages))
+
+	for i := range pages {
+		parent[i] = i
+		similarity[i] = 1
+	}
+
+	root := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+
+		return i
+	}
+
+	// Only pages sharing a band of their signature are compared, each pair
+	// within the first band they share.
+	var compared int
+
+	for band := 0; band < signatureBands; band++ {
+		buckets := make(map[uint64][]int)
+		for i, fp := range pages {
+			buckets[fp.bands[band]] = append(buckets[fp.bands[band]], i)
+		}
+
+		for _, bucket := range buckets {
+			for x, i := range bucket {
+				for _, j := range bucket[x+1:] {
+					a, b := pages[i], pages[j]
+
+					if sharedBand(a, b, band) {
+						continue
+					}
+
+					compared++
+
+					sim := 1.0
+					if a.hash != b.hash {
+						if sim = sketchSimilarity(a.sketch, b.sketch); sim < di.threshold {
+							continue
+						}
+					}
+
+					ri, rj := root(i), root(j)
+					if ri == rj {
+						continue
+					}
+
+					parent[rj] = ri
+					similarity[ri] = minFloat(sim, minFloat(similarity[ri], similarity[rj]))
+				}
+			}
+		}
+	}
+
+	members := make(map[int][]*fingerprint)
+	var roots []int
+
+	for i := range pages {
+		r := root(i)
+		if _, ok := members[r]; !ok {
+			roots = append(roots, r)
+		}
+
+		members[r] = append(members[r], pages[i])
+	}
+
+	var findings []LinkReport
+	di.clusters = nil
+
+	for _, r := range roots {
+		if len(members[r]) < 2 {
+			continue
+		}
+
+		cluster := duplicateCluster(members[r], similarity[r])
+		di.clusters = append(di.clusters, cluster)
+
+		if cluster.Canonical != "" {
+			continue
+		}
+
+		kind := KindDuplicate
+		msg := "Content is identical to %d other pages which do not declare a consistent canonical"
+		if cluster.Kind == DuplicateNear {
+			kind = KindNearDuplicate
+			msg = "Content is similar to %d other pages which do not declare a consistent canonical"
+		}
+
+		for _, p := range cluster.Pages {
+			findings = append(findings, LinkReport{
+				Link:       p.URL,
+				Source:     cluster.Pages[0].URL,
+				Tag:        "link[href]",
+				Reference:  p.Canonical,
+				Error:      fmt.Errorf(msg, len(cluster.Pages)-1),
+				Kind:       kind,
+				Severity:   SeverityWarning,
+				Confidence: cluster.Similarity,
+			})
+		}
+	}
+
+	di.events.Event(context, "Duplicates", "Completed : Pages[%d] : Compared[%d] : Clusters[%d]", len(pages), compared, len(di.clusters))
+
+	return findings
+}
+
+// found returns the clusters of pages with duplicate content found by Finish.
+func (di *duplicateInspector) found() []DuplicateCluster {
+	di.ml.Lock()
+	defer di.ml.Unlock()
+
+	return di.clusters
+}
+
+// duplicateCluster returns the cluster of the giving pages, which are in
+// order.
+func duplicateCluster(pages []*fingerprint, similarity float64) DuplicateCluster {
+	cluster := DuplicateCluster{
+		Kind:       DuplicateExact,
+		Similarity: similarity,
+		Canonical:  pages[0].canonical,
+	}
+
+	for _, fp := range pages {
+		if fp.hash != pages[0].hash {
+			cluster.Kind = DuplicateNear
+		}
+
+		if fp.canonical != cluster.Canonical {
+			cluster.Canonical = ""
+		}
+
+		cluster.Pages = append(cluster.Pages, DuplicatePage{URL: fp.page, Canonical: fp.canonical})
+	}
+
+	return cluster
+}
+
+// fingerprintsByPage sorts fingerprints by the URL of their page.
+type fingerprintsByPage []*fingerprint
+
+func (f fingerprintsByPage) Len() int           { return len(f) }
+func (f fingerprintsByPage) Less(i, j int) bool { return f[i].page < f[j].page }
+func (f fingerprintsByPage) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+
+//==============================================================================
+
+// pageText returns the text of the giving nodes, leaving out the content of
+// elements which are not displayed such as scripts and styles.
+func pageText(nodes ...*html.Node) string {
+	var text []string
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			text = append(text, n.Data)
+			return
+
+		case html.ElementNode:
+			switch n.Data {
+			case "head", "script", "style", "noscript", "template":
+				return
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	for _, n := range nodes {
+		walk(n)
+	}
+
+	return strings.Join(text, " ")
+}
+
+// signature returns the bands of the giving number of rows of minhashes of
+// the giving shingles, where texts sharing most of their shingles likely
+// share some band whole.
+func signature(set map[uint64]bool, rows int) [signatureBands]uint64 {
+	mins := make([]uint64, signatureBands*rows)
+	for i := range mins {
+		mins[i] = math.MaxUint64
+	}
+
+	for s := range set {
+		for i := range mins {
+			if h := mix(s ^ mix(uint64(i+1))); h < mins[i] {
+				mins[i] = h
+			}
+		}
+	}
+
+	var bands [signatureBands]uint64
+	for band := range bands {
+		for _, h := range mins[band*rows : (band+1)*rows] {
+			bands[band] = mix(bands[band] ^ h)
+		}
+	}
+
+	return bands
+}
+
+// mix returns the giving value with its bits mixed, as the finalizer of
+// splitmix64 does.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
+
+// sharedBand reports whether the giving fingerprints share any band of their
+// signature before the giving one.
+func sharedBand(a *fingerprint, b *fingerprint, band int) bool {
+	for i := 0; i < band; i++ {
+		if a.bands[i] == b.bands[i] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sketch returns the lowest sketchSize hashes of the giving shingles, in
+// order.
+func sketch(set map[uint64]bool) []uint64 {
+	hashes := make([]uint64, 0, len(set))
+	for s := range set {
+		hashes = append(hashes, s)
+	}
+
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+
+	// The sketch is copied so the hashes past it can be freed.
+	if len(hashes) > sketchSize {
+		hashes = append([]uint64(nil), hashes[:sketchSize]...)
+	}
+
+	return hashes
+}
+
+// sketchSimilarity returns the similarity, from 0 to 1, of the shingles of two
+// sketches as the share of the lowest hashes of their union found in both.
+func sketchSimilarity(a []uint64, b []uint64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	var i, j, union, shared int
+	for union < sketchSize && (i < len(a) || j < len(b)) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			i++
+		case i == len(a) || b[j] < a[i]:
+			j++
+		default:
+			shared++
+			i++
+			j++
+		}
+
+		union++
+	}
+
+	return float64(shared) / float64(union)
+}
+
+// minFloat returns the lowest of the giving values.
+func minFloat(a float64, b float64) float64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
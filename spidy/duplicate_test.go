@@ -0,0 +1,206 @@
+package spidy_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+// TestDuplicates tests clustering the pages of a site with duplicate content.
+func TestDuplicates(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to find pages with duplicate content")
+	{
+		var words []string
+		for i := 0; i < 80; i++ {
+			words = append(words, fmt.Sprintf("word%d", i))
+		}
+
+		article := strings.Join(words, " ")
+		edited := strings.Replace(article, "word40", "changed", 1)
+
+		// The long article has more shingles than pages keep hashes of, and
+		// its mirror changes a word in every hundred.
+		var terms, mirrored []string
+		for i := 0; i < 2000; i++ {
+			terms = append(terms, fmt.Sprintf("term%d", i))
+
+			if i%100 == 50 {
+				mirrored = append(mirrored, "changed")
+				continue
+			}
+
+			mirrored = append(mirrored, terms[i])
+		}
+
+		long := strings.Join(terms, " ")
+		longEdited := strings.Join(mirrored, " ")
+
+		pages := map[string]string{
+			"/": `<a href="/a">A</a><a href="/a-copy">A</a><a href="/b">B</a><a href="/b-print">B</a><a href="/c">C</a><a href="/d">D</a><a href="/d-mirror">D</a>`,
+
+			"/a":      `<p>The same article</p><script>var a = 1;</script>`,
+			"/a-copy": `<p>The  same  ARTICLE</p><script>var b = 2;</script>`,
+
+			"/b":       `<link rel="canonical" href="/b"><p>` + article + `</p>`,
+			"/b-print": `<link rel="canonical" href="/b"><p>` + edited + `</p>`,
+
+			"/c": `<p>Something else entirely</p>`,
+
+			"/d":        `<link rel="canonical" href="/d"><p>` + long + `</p>`,
+			"/d-mirror": `<link rel="canonical" href="/d"><p>` + longEdited + `</p>`,
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			body, ok := pages[req.URL.Path]
+			if !ok {
+				http.NotFound(res, req)
+				return
+			}
+
+			res.Header().Set("Content-Type", "text/html")
+			res.Write([]byte("<html><head><title>Page</title></head><body>" + body + "</body></html>"))
+		}))
+
+		defer server.Close()
+
+		conf := spidy.Config{
+			Client:     &http.Client{Timeout: 30 * time.Second},
+			URL:        server.URL,
+			Workers:    10,
+			Depth:      -1,
+			Events:     events,
+			Duplicates: &spidy.Duplicates{},
+		}
+
+		t.Logf("\tWhen crawling a site with exact and near duplicate pages")
+		{
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have crawled the site", tests.Success)
+
+			var clusters []string
+			for _, d := range report.Duplicates {
+				var urls []string
+				for _, p := range d.Pages {
+					urls = append(urls, strings.TrimPrefix(p.URL, server.URL))
+				}
+
+				clusters = append(clusters, fmt.Sprintf("%s %v %s", d.Kind, urls, strings.TrimPrefix(d.Canonical, server.URL)))
+			}
+
+			sort.Strings(clusters)
+
+			expected := []string{
+				"exact [/a /a-copy] ",
+				"near [/b /b-print] /b",
+				"near [/d /d-mirror] /d",
+			}
+
+			if strings.Join(clusters, "\n") != strings.Join(expected, "\n") {
+				t.Errorf("\t%s\tShould have clustered the duplicate pages:\n%s\n\tbut got:\n%s", tests.Failed, strings.Join(expected, "\n"), strings.Join(clusters, "\n"))
+			} else {
+				t.Logf("\t%s\tShould have clustered the duplicate pages", tests.Success)
+			}
+
+			var found []string
+			for _, f := range report.Links {
+				found = append(found, f.Kind+" "+strings.TrimPrefix(f.Link, server.URL))
+			}
+
+			sort.Strings(found)
+
+			if strings.Join(found, ",") != "duplicate-content /a,duplicate-content /a-copy" {
+				t.Errorf("\t%s\tShould have only reported the pages without a consistent canonical but got %v", tests.Failed, found)
+			} else {
+				t.Logf("\t%s\tShould have only reported the pages without a consistent canonical", tests.Success)
+			}
+
+			for _, d := range report.Duplicates {
+				if d.Kind == spidy.DuplicateNear && (d.Similarity < 0.9 || d.Similarity >= 1) {
+					t.Errorf("\t%s\tShould have measured the similarity of near duplicates but got %.2f", tests.Failed, d.Similarity)
+				}
+			}
+		}
+
+		t.Logf("\tWhen crawling a site with many distinct pages")
+		{
+			const total = 300
+
+			var links []string
+			for i := 0; i < total; i++ {
+				links = append(links, fmt.Sprintf(`<a href="/p%d">P</a>`, i))
+			}
+
+			site := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				res.Header().Set("Content-Type", "text/html")
+
+				if req.URL.Path == "/" {
+					res.Write([]byte("<html><body>" + strings.Join(links, "") + "</body></html>"))
+					return
+				}
+
+				// Every page shares its navigation but has its own article.
+				var words []string
+				for i := 0; i < 60; i++ {
+					words = append(words, fmt.Sprintf("%s-word%d", req.URL.Path[1:], i))
+				}
+
+				res.Write([]byte("<html><body><nav>Home About Contact Blog</nav><p>" + strings.Join(words, " ") + "</p></body></html>"))
+			}))
+
+			defer site.Close()
+
+			counted := countingEvents{compared: -1}
+
+			conf := conf
+			conf.URL = site.URL
+			conf.Events = &counted
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have crawled the site", tests.Success)
+
+			if len(report.Duplicates) != 0 {
+				t.Errorf("\t%s\tShould have found no duplicate pages but got %d clusters", tests.Failed, len(report.Duplicates))
+			} else {
+				t.Logf("\t%s\tShould have found no duplicate pages", tests.Success)
+			}
+
+			if counted.compared < 0 || counted.compared >= total {
+				t.Errorf("\t%s\tShould have compared fewer pairs of pages than pages but compared %d", tests.Failed, counted.compared)
+			} else {
+				t.Logf("\t%s\tShould have compared fewer pairs of pages than pages", tests.Success)
+			}
+		}
+	}
+}
+
+// countingEvents logs events as Events does, recording the number of pairs of
+// pages compared for duplicate content.
+type countingEvents struct {
+	Events
+	compared int
+}
+
+// Event implements the spidy.Events interface.
+func (ce *countingEvents) Event(context interface{}, event string, format string, data ...interface{}) {
+	if event == "Duplicates" {
+		fmt.Sscanf(fmt.Sprintf(format, data...), "Completed : Pages[%d] : Compared[%d]", new(int), &ce.compared)
+	}
+
+	ce.Events.Event(context, event, format, data...)
+}
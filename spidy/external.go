@@ -0,0 +1,141 @@
+package spidy
+
+import (
+	"sync"
+
+	"github.com/ardanlabs/kit/pool"
+)
+
+// externalChecker checks the links beyond the scope of a crawl on a pool of
+// its own, so slow external hosts don't hold up the pages being crawled. Links
+// are queued as they are found and fed to the pool as its workers are free.
+type externalChecker struct {
+	*crawlState
+	pool   *pool.Pool
+	queue  Frontier
+	notify chan struct{}
+	stop   chan struct{}
+	wait   sync.WaitGroup
+}
+
+// newExternalChecker returns a new externalChecker for the giving crawl, with
+// the giving number of workers.
+func newExternalChecker(cs *crawlState, workers int) (*externalChecker, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	poolCfg := pool.Config{
+		OptEvent: pool.OptEvent{Event: cs.config.Events.Event},
+		MinRoutines: func() int {
+			if workers < 10 {
+				return workers
+			}
+
+			return 10
+		},
+		MaxRoutines: func() int { return workers },
+	}
+
+	pl, err := pool.New("spidy", "externals", poolCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ec := externalChecker{
+		crawlState: cs,
+		pool:       pl,
+		queue:      NewMemoryFrontier(nil),
+		notify:     make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+	}
+
+	go ec.feed()
+
+	return &ec, nil
+}
+
+// check queues the link of the giving entry to be checked.
+func (ec *externalChecker) check(context interface{}, entry FrontierEntry) {
+	ec.wait.Add(1)
+
+	if err := ec.queue.Push(entry); err != nil {
+		ec.config.Events.ErrorEvent(context, "check", err, "URL[%s]", entry.URL)
+		ec.wait.Done()
+		return
+	}
+
+	select {
+	case ec.notify <- struct{}{}:
+	default:
+	}
+}
+
+// feed hands the queued links to the pool until the checker is closed.
+func (ec *externalChecker) feed() {
+	for {
+		if entry, ok := ec.queue.Pop(); ok {
+			ec.pool.Do("externals", &externalBot{externalChecker: ec, entry: entry})
+			continue
+		}
+
+		select {
+		case <-ec.notify:
+		case <-ec.stop:
+			return
+		}
+	}
+}
+
+// close waits for every queued link to be checked and shuts the pool down.
+func (ec *externalChecker) close() {
+	ec.wait.Wait()
+	close(ec.stop)
+	ec.pool.Shutdown("spidy")
+}
+
+// externalBot provides a worker which checks a link beyond the scope of the
+// crawl with a single request, without parsing it. It implements pool.Work
+// interface.
+type externalBot struct {
+	*externalChecker
+	entry FrontierEntry
+}
+
+// Work checks the link of the bot.
+func (e *externalBot) Work(context interface{}, id int) {
+	defer e.wait.Done()
+
+	link := e.entry.link()
+
+	// Links still queued once the budget is spent are left unexplored.
+	if e.budget.spent() {
+		e.budget.skip()
+		return
+	}
+
+	status, _, err := evaluatePath(e.entry.URL, e.config)
+	if err != nil {
+		if e.budget.refused(err) {
+			e.budget.skip()
+			return
+		}
+
+		report := link.describe(newLinkReport(e.entry.URL, e.entry.Source, status, err))
+		report.Site = e.entry.Site
+		e.dead <- report
+		return
+	}
+
+	// A resource which answers but is not what the page loads it as is still
+	// broken.
+	if resource := resourceType(link); e.config.Integrity && resource != "" {
+		if status, err := checkResource(e.entry.URL, resource, e.config); err != nil && !e.budget.refused(err) {
+			report := newLinkReport(e.entry.URL, e.entry.Source, status, err)
+			report.Kind = KindIntegrity
+			report = link.describe(report)
+			report.Site = e.entry.Site
+			e.dead <- report
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package spidy_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+// TestExternalChecks tests checking the links beyond the scope of a crawl with
+// a single request each on a pool of their own.
+func TestExternalChecks(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to check external links without crawling them")
+	{
+		const pages = 20
+
+		var ml sync.Mutex
+		requests := make(map[string][]string)
+		crawled := make(chan struct{})
+
+		external := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			ml.Lock()
+			requests[req.URL.Path] = append(requests[req.URL.Path], req.Method)
+			ml.Unlock()
+
+			switch {
+			case req.URL.Path == "/missing":
+				http.NotFound(res, req)
+				return
+
+			// Slow links answer once every internal page is crawled, or give
+			// up and report it was held up.
+			case strings.HasPrefix(req.URL.Path, "/slow"):
+				select {
+				case <-crawled:
+				case <-time.After(5 * time.Second):
+					res.WriteHeader(http.StatusGatewayTimeout)
+					return
+				}
+			}
+
+			res.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(res, `<html><body><a href="/beyond">Beyond</a></body></html>`)
+		}))
+
+		defer external.Close()
+
+		// Each page links to the next, a slow external link of its own and
+		// the same shared external links.
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			var page int
+			fmt.Sscanf(req.URL.Path, "/%d", &page)
+
+			if req.Method == "GET" && page == pages-1 {
+				close(crawled)
+			}
+
+			res.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(res, `<html><body>`)
+
+			if page < pages-1 {
+				fmt.Fprintf(res, `<a href="/%d">Next</a>`, page+1)
+			}
+
+			fmt.Fprintf(res, `<a href="%s/slow/%d">Slow</a><a href="%s/shared">Shared</a><a href="%s/missing">Missing</a></body></html>`, external.URL, page, external.URL, external.URL)
+		}))
+
+		defer server.Close()
+
+		conf := spidy.Config{
+			Client:          &http.Client{Timeout: 30 * time.Second},
+			URL:             server.URL + "/0",
+			All:             true,
+			Workers:         10,
+			ExternalWorkers: 2,
+			Depth:           -1,
+			Events:          events,
+		}
+
+		t.Logf("\tWhen crawling a site linking to a slow external site")
+		{
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+
+			var found []string
+			for _, f := range report.Links {
+				found = append(found, f.Kind+" "+strings.TrimPrefix(f.Link, external.URL))
+			}
+
+			if strings.Join(found, ",") != "dead-link /missing" {
+				t.Errorf("\t%s\tShould have reported the dead external link once without holding up the crawl but got %v", tests.Failed, found)
+			} else {
+				t.Logf("\t%s\tShould have reported the dead external link once without holding up the crawl", tests.Success)
+			}
+
+			ml.Lock()
+			defer ml.Unlock()
+
+			var twice []string
+			for path, methods := range requests {
+				if len(methods) != 1 || methods[0] != "HEAD" {
+					twice = append(twice, path+" "+strings.Join(methods, "+"))
+				}
+			}
+
+			if len(requests) != pages+2 || len(twice) > 0 {
+				t.Errorf("\t%s\tShould have checked each external link with a single request but got %d links and %v", tests.Failed, len(requests), twice)
+			} else {
+				t.Logf("\t%s\tShould have checked each external link with a single request", tests.Success)
+			}
+		}
+	}
+}
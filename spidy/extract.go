@@ -0,0 +1,217 @@
+package spidy
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// extractor defines how links are extracted from the value of an attribute
+// and what the extracted links are referenced as.
+type extractor struct {
+	reference string
+	extract   func(value string) []string
+}
+
+// extractors maps the element and attribute pairs that hold links to the
+// extractor for them. Pairs using the "*" element apply to any element not
+// matched by a more specific pair.
+var extractors = map[string]extractor{
+	"a[href]":            {"hyperlink", single},
+	"area[href]":         {"hyperlink", single},
+	"link[href]":         {"link", single},
+	"link[imagesrcset]":  {"srcset candidate", srcset},
+	"img[src]":           {"image", single},
+	"img[srcset]":        {"srcset candidate", srcset},
+	"img[longdesc]":      {"image description", single},
+	"source[src]":        {"media source", single},
+	"source[srcset]":     {"srcset candidate", srcset},
+	"script[src]":        {"script", single},
+	"iframe[src]":        {"frame", single},
+	"frame[src]":         {"frame", single},
+	"embed[src]":         {"embedded resource", single},
+	"object[data]":       {"object data", single},
+	"audio[src]":         {"media source", single},
+	"video[src]":         {"media source", single},
+	"video[poster]":      {"video poster", single},
+	"track[src]":         {"text track", single},
+	"input[src]":         {"image", single},
+	"form[action]":       {"form action", single},
+	"button[formaction]": {"form action", single},
+	"input[formaction]":  {"form action", single},
+	"blockquote[cite]":   {"citation", single},
+	"q[cite]":            {"citation", single},
+	"del[cite]":          {"citation", single},
+	"ins[cite]":          {"citation", single},
+	"html[manifest]":     {"manifest", single},
+	"body[background]":   {"background image", single},
+	"meta[content]":      {"meta refresh target", refresh},
+	"base[href]":         {"", none},
+	"*[xlink:href]":      {"SVG reference", single},
+	"*[href]":            {"link", single},
+	"*[src]":             {"resource", single},
+}
+
+// linkRels maps the rel values of <link> elements to what they are referenced
+// as.
+var linkRels = map[string]string{
+	"stylesheet":       "stylesheet",
+	"icon":             "icon",
+	"apple-touch-icon": "icon",
+	"manifest":         "manifest",
+	"preload":          "preload",
+	"modulepreload":    "preload",
+	"prefetch":         "prefetch",
+	"canonical":        "canonical link",
+	"alternate":        "alternate link",
+}
+
+// ignoredSchemes are the schemes of links which can't be fetched.
+var ignoredSchemes = []string{"javascript:", "mailto:", "tel:", "data:", "about:", "sms:"}
+
+//==============================================================================
+
+// extractLinks returns the links within the attributes of every element of
+// the giving document, in document order.
+func extractLinks(doc *goquery.Document) []pageLink {
+	var links []pageLink
+
+	doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+		node := s.Get(0)
+
+		for _, attr := range node.Attr {
+			links = append(links, extractAttr(node, attr)...)
+		}
+	})
+
+	return links
+}
+
+// extractAttr returns the links within the giving attribute of a node.
+func extractAttr(node *html.Node, attr html.Attribute) []pageLink {
+	key := attr.Key
+	if attr.Namespace != "" {
+		key = attr.Namespace + ":" + key
+	}
+
+	ex, ok := extractors[node.Data+"["+key+"]"]
+	if !ok {
+		if ex, ok = extractors["*["+key+"]"]; !ok {
+			return nil
+		}
+	}
+
+	// Only refresh meta elements hold links within their content.
+	if node.Data == "meta" {
+		equiv, _ := getAttr(node.Attr, "http-equiv")
+		if !strings.EqualFold(equiv.Val, "refresh") {
+			return nil
+		}
+	}
+
+	rel, _ := getAttr(node.Attr, "rel")
+
+	reference := ex.reference
+	if node.Data == "link" && key == "href" {
+		reference = linkReference(rel.Val)
+	}
+
+	var links []pageLink
+	for _, value := range ex.extract(attr.Val) {
+		if ignoredLink(value) {
+			continue
+		}
+
+		links = append(links, pageLink{
+			URL:       value,
+			Element:   node.Data,
+			Attr:      key,
+			Rel:       rel.Val,
+			Reference: reference,
+		})
+	}
+
+	return links
+}
+
+// linkReference returns what a <link> element is referenced as based on its
+// rel attribute.
+func linkReference(rel string) string {
+	for _, field := range strings.Fields(strings.ToLower(rel)) {
+		if reference, ok := linkRels[field]; ok {
+			return reference
+		}
+	}
+
+	return "link"
+}
+
+// ignoredLink reports whether the giving link is empty or uses a scheme which
+// can't be fetched.
+func ignoredLink(link string) bool {
+	link = strings.ToLower(strings.TrimSpace(link))
+	if link == "" {
+		return true
+	}
+
+	for _, scheme := range ignoredSchemes {
+		if strings.HasPrefix(link, scheme) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//==============================================================================
+
+// none returns no links, for attributes which hold URLs that aren't links.
+func none(value string) []string {
+	return nil
+}
+
+// single returns the attribute value as the only link.
+func single(value string) []string {
+	return []string{strings.TrimSpace(value)}
+}
+
+// srcset returns the candidate URLs of a srcset attribute, a comma separated
+// list of URLs each optionally followed by a width or density descriptor.
+func srcset(value string) []string {
+	var links []string
+
+	for _, candidate := range strings.Split(value, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+
+		links = append(links, fields[0])
+	}
+
+	return links
+}
+
+// refresh returns the target URL of a meta refresh content attribute, which
+// has the form "5; url=/next".
+func refresh(value string) []string {
+	parts := strings.SplitN(value, ";", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+
+	target := strings.TrimSpace(parts[1])
+	if len(target) > 3 && strings.EqualFold(target[:3], "url") {
+		target = strings.TrimSpace(target[3:])
+		target = strings.TrimSpace(strings.TrimPrefix(target, "="))
+	}
+
+	target = strings.Trim(target, `"'`)
+	if target == "" {
+		return nil
+	}
+
+	return []string{target}
+}
@@ -0,0 +1,138 @@
+package spidy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// extractPage provides a page referencing links from every kind of element
+// and attribute spidy extracts, all of which are dead.
+var extractPage = []byte(`
+<!DOCTYPE html>
+<html manifest="/dead/app.appcache">
+<head>
+	<meta http-equiv="refresh" content="30; url='/dead/refresh'">
+	<meta name="description" content="/not/a/link">
+	<link rel="stylesheet" href="/dead/site.css">
+	<link rel="preload" href="/dead/font.woff2" as="font">
+	<link rel="manifest" href="/dead/manifest.json">
+	<link rel="icon" href="/dead/favicon.ico">
+	<link rel="preload" as="image" imagesrcset="/dead/hero-1x.png 1x, /dead/hero-2x.png 2x">
+	<script src="/dead/app.js"></script>
+</head>
+<body background="/dead/body.png">
+	<a href="/dead/page">Page</a>
+	<a href="javascript:void(0)">Nothing</a>
+	<a href="mailto:bob@example.com">Mail</a>
+	<map><area href="/dead/area"></map>
+	<img src="/dead/img.png" srcset="/dead/img-320.png 320w, /dead/img-640.png 640w">
+	<picture><source srcset="/dead/source.webp"></picture>
+	<video src="/dead/video.mp4" poster="/dead/poster.png"><track src="/dead/captions.vtt"></video>
+	<audio src="/dead/audio.mp3"></audio>
+	<iframe src="/dead/frame"></iframe>
+	<object data="/dead/object.swf"></object>
+	<embed src="/dead/embed.swf">
+	<form action="/dead/form"><input type="image" src="/dead/input.png"><button formaction="/dead/button">Go</button></form>
+	<blockquote cite="/dead/quote">Quote</blockquote>
+	<q cite="/dead/q">Q</q>
+	<svg><use xlink:href="/dead/sprite.svg#icon"></use></svg>
+</body>
+</html>`)
+
+// TestExtraction tests the links extracted from every element and attribute
+// within a page are checked and tagged with where they were found.
+func TestExtraction(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to check every link within a page")
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != "/" {
+				res.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			res.Write(extractPage)
+		}))
+
+		defer server.Close()
+
+		conf := spidy.Config{
+			Client:  &http.Client{Timeout: 30 * time.Second},
+			URL:     server.URL,
+			Workers: 30,
+			Depth:   -1,
+			Events:  events,
+		}
+
+		expected := map[string][2]string{
+			"/dead/app.appcache":  {"html[manifest]", "manifest"},
+			"/dead/refresh":       {"meta[content]", "meta refresh target"},
+			"/dead/site.css":      {"link[href]", "stylesheet"},
+			"/dead/font.woff2":    {"link[href]", "preload"},
+			"/dead/manifest.json": {"link[href]", "manifest"},
+			"/dead/favicon.ico":   {"link[href]", "icon"},
+			"/dead/hero-1x.png":   {"link[imagesrcset]", "srcset candidate"},
+			"/dead/hero-2x.png":   {"link[imagesrcset]", "srcset candidate"},
+			"/dead/app.js":        {"script[src]", "script"},
+			"/dead/body.png":      {"body[background]", "background image"},
+			"/dead/page":          {"a[href]", "hyperlink"},
+			"/dead/area":          {"area[href]", "hyperlink"},
+			"/dead/img.png":       {"img[src]", "image"},
+			"/dead/img-320.png":   {"img[srcset]", "srcset candidate"},
+			"/dead/img-640.png":   {"img[srcset]", "srcset candidate"},
+			"/dead/source.webp":   {"source[srcset]", "srcset candidate"},
+			"/dead/video.mp4":     {"video[src]", "media source"},
+			"/dead/poster.png":    {"video[poster]", "video poster"},
+			"/dead/captions.vtt":  {"track[src]", "text track"},
+			"/dead/audio.mp3":     {"audio[src]", "media source"},
+			"/dead/frame":         {"iframe[src]", "frame"},
+			"/dead/object.swf":    {"object[data]", "object data"},
+			"/dead/embed.swf":     {"embed[src]", "embedded resource"},
+			"/dead/form":          {"form[action]", "form action"},
+			"/dead/input.png":     {"input[src]", "image"},
+			"/dead/button":        {"button[formaction]", "form action"},
+			"/dead/quote":         {"blockquote[cite]", "citation"},
+			"/dead/q":             {"q[cite]", "citation"},
+			"/dead/sprite.svg":    {"use[xlink:href]", "SVG reference"},
+		}
+
+		t.Logf("\tWhen crawling a page with links in every kind of element")
+		{
+			badlinks, err := spidy.Run(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have successfully retrieved page[%s]: %q", tests.Failed, conf.URL, err)
+			}
+			t.Logf("\t%s\tShould have successfully retrieved page[%s]", tests.Success, conf.URL)
+
+			if len(badlinks) != len(expected) {
+				t.Fatalf("\t%s\tShould have found %d dead links: %+v", tests.Failed, len(expected), badlinks)
+			}
+			t.Logf("\t%s\tShould have found %d dead links", tests.Success, len(expected))
+
+			for _, report := range badlinks {
+				path := strings.TrimPrefix(report.Link, server.URL)
+				path = strings.Split(path, "#")[0]
+
+				tag, ok := expected[path]
+				if !ok {
+					t.Fatalf("\t%s\tShould have expected dead link[%s]", tests.Failed, report.Link)
+				}
+
+				if report.Tag != tag[0] || report.Reference != tag[1] {
+					t.Fatalf("\t%s\tShould have tagged dead link[%s] as %s %s: %+v", tests.Failed, path, tag[0], tag[1], report)
+				}
+			}
+			t.Logf("\t%s\tShould have tagged every dead link with where it was found", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package spidy
+
+import (
+	"container/heap"
+	"hash/fnv"
+	"sync"
+)
+
+// FrontierEntry defines a page pending within a Frontier, with the link it was
+// found by.
+type FrontierEntry struct {
+	URL    string `json:"url"`
+	Source string `json:"source,omitempty"`
+	Site   string `json:"site,omitempty"`
+
+	// Element, Attr, Rel and Reference describe the link the page was found
+	// by within its source.
+	Element   string `json:"element,omitempty"`
+	Attr      string `json:"attr,omitempty"`
+	Rel       string `json:"rel,omitempty"`
+	Reference string `json:"reference,omitempty"`
+
+	// Depth is the number of links the page is from the seeds of the crawl.
+	Depth int `json:"depth,omitempty"`
+
+	// Checked is set if the URL was already checked, so it is fetched
+	// without being checked again.
+	Checked bool `json:"checked,omitempty"`
+}
+
+// newEntry returns the entry of the page at the giving URL found by the giving
+// link.
+func newEntry(url string, link pageLink, source string, site string, depth int) FrontierEntry {
+	return FrontierEntry{
+		URL:       url,
+		Source:    source,
+		Site:      site,
+		Depth:     depth,
+		Element:   link.Element,
+		Attr:      link.Attr,
+		Rel:       link.Rel,
+		Reference: link.Reference,
+	}
+}
+
+// link returns the link the page of the entry was found by.
+func (e FrontierEntry) link() pageLink {
+	return pageLink{URL: e.URL, Element: e.Element, Attr: e.Attr, Rel: e.Rel, Reference: e.Reference}
+}
+
+// Frontier defines an interface for the pages pending within a crawl, which
+// are pushed as they are found and popped as workers are free to crawl them.
+// Frontiers must be safe to use concurrently.
+type Frontier interface {
+	Push(entry FrontierEntry) error
+	Pop() (FrontierEntry, bool)
+	Len() int
+}
+
+// VisitedSet defines an interface for the links a crawl has visited, so no
+// link is checked twice. Visit marks the giving link as visited, reporting
+// whether it already was. VisitedSets must be safe to use concurrently.
+type VisitedSet interface {
+	Visit(link string) bool
+}
+
+//==============================================================================
+
+// memoryFrontier holds the pending pages of a crawl in memory, popping the
+// page with the highest priority first. It implements the Frontier interface.
+type memoryFrontier struct {
+	priority PriorityFunc
+
+	ml      sync.Mutex
+	entries scoredEntries
+}
+
+// NewMemoryFrontier returns a new Frontier held in memory, scoring pages with
+// the giving PriorityFunc or breadth first if it's nil. It is the default of a
+// crawl.
+func NewMemoryFrontier(priority PriorityFunc) Frontier {
+	if priority == nil {
+		priority = DepthPriority
+	}
+
+	return &memoryFrontier{priority: priority}
+}
+
+// Push implements the Frontier interface.
+func (f *memoryFrontier) Push(entry FrontierEntry) error {
+
+	// Pages are scored before the lock is taken, as a PriorityFunc may read
+	// from the site.
+	score := f.priority(entry)
+
+	f.ml.Lock()
+	defer f.ml.Unlock()
+
+	heap.Push(&f.entries, scoredEntry{entry: entry, score: score})
+	return nil
+}
+
+// Pop implements the Frontier interface.
+func (f *memoryFrontier) Pop() (FrontierEntry, bool) {
+	f.ml.Lock()
+	defer f.ml.Unlock()
+
+	if len(f.entries) == 0 {
+		return FrontierEntry{}, false
+	}
+
+	return heap.Pop(&f.entries).(scoredEntry).entry, true
+}
+
+// Len implements the Frontier interface.
+func (f *memoryFrontier) Len() int {
+	f.ml.Lock()
+	defer f.ml.Unlock()
+
+	return len(f.entries)
+}
+
+// scoredEntry defines a pending page with its priority.
+type scoredEntry struct {
+	entry FrontierEntry
+	score float64
+}
+
+// scoredEntries implements heap.Interface, ordering pages by their priority,
+// then depth and then URL so they always pop in the same order.
+type scoredEntries []scoredEntry
+
+func (s scoredEntries) Len() int      { return len(s) }
+func (s scoredEntries) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s scoredEntries) Less(i, j int) bool {
+	switch {
+	case s[i].score != s[j].score:
+		return s[i].score > s[j].score
+	case s[i].entry.Depth != s[j].entry.Depth:
+		return s[i].entry.Depth < s[j].entry.Depth
+	default:
+		return s[i].entry.URL < s[j].entry.URL
+	}
+}
+
+func (s *scoredEntries) Push(x interface{}) { *s = append(*s, x.(scoredEntry)) }
+
+func (s *scoredEntries) Pop() interface{} {
+	old := *s
+	last := old[len(old)-1]
+	old[len(old)-1] = scoredEntry{}
+	*s = old[:len(old)-1]
+
+	return last
+}
+
+//==============================================================================
+
+// memoryVisited holds the visited links of a crawl in a single map. It
+// implements the VisitedSet interface.
+type memoryVisited struct {
+	ml    sync.Mutex
+	links map[string]bool
+}
+
+// NewMemoryVisited returns a new VisitedSet held in a single map in memory,
+// the default of a crawl.
+func NewMemoryVisited() VisitedSet {
+	return &memoryVisited{links: make(map[string]bool)}
+}
+
+// Visit implements the VisitedSet interface.
+func (v *memoryVisited) Visit(link string) bool {
+	v.ml.Lock()
+	defer v.ml.Unlock()
+
+	found := v.links[link]
+	v.links[link] = true
+
+	return found
+}
+
+// shardedVisited spreads the visited links of a crawl over several maps, each
+// with its own lock, so workers rarely contend on the same lock. It
+// implements the VisitedSet interface.
+type shardedVisited struct {
+	shards []memoryVisited
+}
+
+// NewShardedVisited returns a new VisitedSet held in memory across the giving
+// number of shards, for crawls with many workers.
+func NewShardedVisited(shards int) VisitedSet {
+	if shards < 1 {
+		shards = 1
+	}
+
+	v := shardedVisited{shards: make([]memoryVisited, shards)}
+	for i := range v.shards {
+		v.shards[i].links = make(map[string]bool)
+	}
+
+	return &v
+}
+
+// Visit implements the VisitedSet interface.
+func (v *shardedVisited) Visit(link string) bool {
+	h := fnv.New32a()
+	h.Write([]byte(link))
+
+	return v.shards[h.Sum32()%uint32(len(v.shards))].Visit(link)
+}
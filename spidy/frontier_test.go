@@ -0,0 +1,265 @@
+package spidy_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+// TestFrontiers tests the frontiers pages pending within a crawl are held in.
+func TestFrontiers(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	dir, err := ioutil.TempDir("", "spidy-frontier")
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a directory: %q", tests.Failed, err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	disk, err := spidy.NewDiskFrontier(filepath.Join(dir, "frontier.log"))
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a disk frontier: %q", tests.Failed, err)
+	}
+
+	defer disk.Close()
+
+	frontiers := map[string]spidy.Frontier{
+		"memory": spidy.NewMemoryFrontier(nil),
+		"disk":   disk,
+	}
+
+	t.Logf("Given the need to hold the pages pending within a crawl")
+	{
+		for name, f := range frontiers {
+			t.Logf("\tWhen pushing and popping pages of a %s frontier", name)
+			{
+				var popped []string
+
+				for i := 0; i < 10; i++ {
+					if err := f.Push(spidy.FrontierEntry{URL: fmt.Sprintf("/%d", i), Element: "a", Attr: "href"}); err != nil {
+						t.Fatalf("\t%s\tShould be able to push a page: %q", tests.Failed, err)
+					}
+
+					// Pops interleaved with pushes read back what's been
+					// pushed so far.
+					if i%3 == 0 {
+						entry, ok := f.Pop()
+						if !ok {
+							t.Fatalf("\t%s\tShould be able to pop a page", tests.Failed)
+						}
+
+						popped = append(popped, entry.URL)
+					}
+				}
+
+				if f.Len() != 6 {
+					t.Errorf("\t%s\tShould have 6 pages pending but got %d", tests.Failed, f.Len())
+				}
+
+				for {
+					entry, ok := f.Pop()
+					if !ok {
+						break
+					}
+
+					if entry.Element != "a" || entry.Attr != "href" {
+						t.Errorf("\t%s\tShould have kept the link of the page but got %+v", tests.Failed, entry)
+					}
+
+					popped = append(popped, entry.URL)
+				}
+
+				if strings.Join(popped, ",") != "/0,/1,/2,/3,/4,/5,/6,/7,/8,/9" || f.Len() != 0 {
+					t.Errorf("\t%s\tShould have popped the pages in order but got %v", tests.Failed, popped)
+				} else {
+					t.Logf("\t%s\tShould have popped the pages in order", tests.Success)
+				}
+			}
+		}
+	}
+}
+
+// TestVisitedSets tests the sets the visited links of a crawl are held in.
+func TestVisitedSets(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	dir, err := ioutil.TempDir("", "spidy-visited")
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a directory: %q", tests.Failed, err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "visited.log")
+
+	disk, err := spidy.NewDiskVisited(path, 1000, 0)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a disk visited set: %q", tests.Failed, err)
+	}
+
+	sets := map[string]spidy.VisitedSet{
+		"memory":  spidy.NewMemoryVisited(),
+		"sharded": spidy.NewShardedVisited(8),
+		"disk":    disk,
+	}
+
+	t.Logf("Given the need to hold the links a crawl has visited")
+	{
+		for name, v := range sets {
+			t.Logf("\tWhen visiting links of a %s set", name)
+			{
+				var again int
+				for i := 0; i < 500; i++ {
+					if v.Visit(fmt.Sprintf("http://example.com/%d", i)) {
+						again++
+					}
+				}
+
+				for i := 0; i < 500; i++ {
+					if !v.Visit(fmt.Sprintf("http://example.com/%d", i)) {
+						again = -1
+					}
+				}
+
+				if again != 0 {
+					t.Errorf("\t%s\tShould have only reported links visited before as visited", tests.Failed)
+				} else {
+					t.Logf("\t%s\tShould have only reported links visited before as visited", tests.Success)
+				}
+			}
+		}
+
+		t.Logf("\tWhen reopening a disk visited set")
+		{
+			if err := disk.Close(); err != nil {
+				t.Fatalf("\t%s\tShould be able to close the set: %q", tests.Failed, err)
+			}
+
+			reopened, err := spidy.NewDiskVisited(path, 1000, 0)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to reopen the set: %q", tests.Failed, err)
+			}
+
+			defer reopened.Close()
+
+			if !reopened.Visit("http://example.com/42") || reopened.Visit("http://example.com/new") {
+				t.Errorf("\t%s\tShould have rebuilt the set from its log", tests.Failed)
+			} else {
+				t.Logf("\t%s\tShould have rebuilt the set from its log", tests.Success)
+			}
+		}
+	}
+}
+
+// TestDiskCrawl tests crawling a site with its frontier and visited links held
+// on disk.
+func TestDiskCrawl(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to crawl a site in bounded memory")
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if strings.HasPrefix(req.URL.Path, "/missing") {
+				http.NotFound(res, req)
+				return
+			}
+
+			res.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(res, `<html><body><a href="/">Home</a>`)
+
+			for i := 0; i < 5; i++ {
+				fmt.Fprintf(res, `<a href="%s/%d">Child</a><a href="/missing%s/%d">Missing</a>`, req.URL.Path, i, req.URL.Path, i)
+			}
+
+			fmt.Fprintf(res, `</body></html>`)
+		}))
+
+		defer server.Close()
+
+		crawl := func(frontier spidy.Frontier, visited spidy.VisitedSet) []string {
+			conf := spidy.Config{
+				Client:   &http.Client{Timeout: 30 * time.Second},
+				URL:      server.URL,
+				Workers:  10,
+				Depth:    -1,
+				Events:   events,
+				Frontier: frontier,
+				Visited:  visited,
+				Budget:   spidy.Budget{Pages: 40},
+			}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+
+			var found []string
+			for _, f := range report.Links {
+				if f.Kind == spidy.KindDeadLink {
+					found = append(found, f.Link)
+				}
+			}
+
+			sort.Strings(found)
+			return found
+		}
+
+		dir, err := ioutil.TempDir("", "spidy-crawl")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a directory: %q", tests.Failed, err)
+		}
+
+		defer os.RemoveAll(dir)
+
+		frontier, err := spidy.NewDiskFrontier(filepath.Join(dir, "frontier.log"))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a disk frontier: %q", tests.Failed, err)
+		}
+
+		defer frontier.Close()
+
+		visited, err := spidy.NewDiskVisited(filepath.Join(dir, "visited.log"), 100000, 0)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a disk visited set: %q", tests.Failed, err)
+		}
+
+		defer visited.Close()
+
+		t.Logf("\tWhen crawling with the frontier and visited links on disk")
+		{
+			found := crawl(frontier, visited)
+
+			if len(found) == 0 {
+				t.Errorf("\t%s\tShould have found the dead links", tests.Failed)
+			} else {
+				t.Logf("\t%s\tShould have found the dead links", tests.Success)
+			}
+
+			for i := 1; i < len(found); i++ {
+				if found[i] == found[i-1] {
+					t.Errorf("\t%s\tShould have checked each link once but got %s twice", tests.Failed, found[i])
+				}
+			}
+
+			if frontier.Len() != 0 {
+				t.Errorf("\t%s\tShould have drained the frontier but %d pages are pending", tests.Failed, frontier.Len())
+			} else {
+				t.Logf("\t%s\tShould have drained the frontier", tests.Success)
+			}
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package spidy
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Formats link graphs can be written in, in addition to FormatJSON.
+const (
+	FormatDOT     = "dot"
+	FormatGraphML = "graphml"
+)
+
+// maxMostBroken limits how many pages are listed as having the most broken
+// outgoing links.
+const maxMostBroken = 10
+
+// LinkGraph defines the internal link graph of a crawl, the hyperlinks between
+// the pages within its scope, along with its analytics.
+type LinkGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+
+	// Orphans are pages listed within a sitemap which no page links to,
+	// DeadEnds are crawled pages which link to no other page and MostBroken
+	// are the pages with the most broken outgoing links, most first.
+	Orphans    []string `json:"orphans,omitempty"`
+	DeadEnds   []string `json:"deadEnds,omitempty"`
+	MostBroken []string `json:"mostBroken,omitempty"`
+}
+
+// GraphNode defines a page within a LinkGraph.
+type GraphNode struct {
+	URL string `json:"url"`
+
+	// Depth is the number of clicks the page is from the nearest seed, or -1
+	// if no path of links leads to it.
+	Depth     int  `json:"depth"`
+	InDegree  int  `json:"inDegree"`
+	OutDegree int  `json:"outDegree"`
+	Broken    int  `json:"broken"`
+	Crawled   bool `json:"crawled"`
+	Orphan    bool `json:"orphan,omitempty"`
+	DeadEnd   bool `json:"deadEnd,omitempty"`
+}
+
+// GraphEdge defines a hyperlink from one page to another within a LinkGraph.
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+//==============================================================================
+
+// linkGraph records the links found on the pages of a crawl as they are
+// crawled.
+type linkGraph struct {
+	ml    sync.Mutex
+	pages map[string]bool
+	edges map[string]map[string]bool
+	links map[string]map[string]bool
+}
+
+// newLinkGraph returns a new linkGraph for the config, or nil if the link
+// graph is not recorded.
+func newLinkGraph(c *Config) *linkGraph {
+	if !c.Graph {
+		return nil
+	}
+
+	return &linkGraph{
+		pages: make(map[string]bool),
+		edges: make(map[string]map[string]bool),
+		links: make(map[string]map[string]bool),
+	}
+}
+
+// page records the giving page as crawled.
+func (g *linkGraph) page(page string) {
+	g.ml.Lock()
+	defer g.ml.Unlock()
+
+	g.pages[page] = true
+}
+
+// link records a link found on the giving page, every link counts towards
+// the broken links of the page but only internal hyperlinks are edges of the
+// graph.
+func (g *linkGraph) link(page string, l pageLink, link string, internal bool) {
+	g.ml.Lock()
+	defer g.ml.Unlock()
+
+	addEdge(g.links, page, link)
+
+	if internal && (l.Element == "a" || l.Element == "area") && link != page {
+		addEdge(g.edges, page, link)
+	}
+}
+
+// build returns the recorded graph with its analytics, counting the links
+// which the giving findings report as broken and the pages listed within the
+// sitemaps of the crawled hosts, read with the giving config.
+func (g *linkGraph) build(c *Config, seeds []seed, findings []LinkReport) *LinkGraph {
+	g.ml.Lock()
+	defer g.ml.Unlock()
+
+	broken := make(map[string]bool)
+	for _, f := range findings {
+		if f.Severity != SeverityError {
+			continue
+		}
+
+		switch f.Kind {
+		case KindDeadLink, KindCertificate, KindIntegrity:
+			broken[f.Link] = true
+		}
+	}
+
+	nodes := make(map[string]*GraphNode)
+	node := func(page string) *GraphNode {
+		n, ok := nodes[page]
+		if !ok {
+			n = &GraphNode{URL: page, Depth: -1}
+			nodes[page] = n
+		}
+
+		return n
+	}
+
+	hosts := make(map[string]bool)
+	for page := range g.pages {
+		node(page).Crawled = true
+
+		if uri, err := url.Parse(page); err == nil {
+			hosts[uri.Scheme+"://"+uri.Host] = true
+		}
+	}
+
+	var edges []GraphEdge
+	for source, targets := range g.edges {
+		for target := range targets {
+			node(source).OutDegree++
+			node(target).InDegree++
+			edges = append(edges, GraphEdge{Source: source, Target: target})
+		}
+	}
+
+	sort.Sort(edgesBySource(edges))
+
+	// Click depths are the shortest paths of links from the seeds.
+	var queue []string
+	for _, s := range seeds {
+		n := node(s.url.String())
+		if n.Depth != 0 {
+			n.Depth = 0
+			queue = append(queue, n.URL)
+		}
+	}
+
+	for len(queue) > 0 {
+		page := queue[0]
+		queue = queue[1:]
+
+		for target := range g.edges[page] {
+			if n := node(target); n.Depth < 0 {
+				n.Depth = nodes[page].Depth + 1
+				queue = append(queue, target)
+			}
+		}
+	}
+
+	var graph LinkGraph
+
+	for _, host := range sortedKeys(hosts) {
+		for _, u := range readSitemap(host, c) {
+			uri, err := url.Parse(u.Loc)
+			if err != nil || !uri.IsAbs() || !inScope(seeds, uri) {
+				continue
+			}
+
+			uri.Fragment = ""
+			if n := node(uri.String()); n.InDegree == 0 && n.Depth != 0 {
+				n.Orphan = true
+			}
+		}
+	}
+
+	for page, links := range g.links {
+		for link := range links {
+			if broken[link] {
+				node(page).Broken++
+			}
+		}
+	}
+
+	var pages []string
+	for page := range nodes {
+		pages = append(pages, page)
+	}
+
+	sort.Strings(pages)
+
+	var mostBroken []*GraphNode
+
+	for _, page := range pages {
+		n := nodes[page]
+		n.DeadEnd = n.Crawled && n.OutDegree == 0
+
+		if n.Orphan {
+			graph.Orphans = append(graph.Orphans, page)
+		}
+
+		if n.DeadEnd {
+			graph.DeadEnds = append(graph.DeadEnds, page)
+		}
+
+		if n.Broken > 0 {
+			mostBroken = append(mostBroken, n)
+		}
+
+		graph.Nodes = append(graph.Nodes, *n)
+	}
+
+	sort.SliceStable(mostBroken, func(i, j int) bool {
+		return mostBroken[i].Broken > mostBroken[j].Broken
+	})
+
+	for i, n := range mostBroken {
+		if i == maxMostBroken {
+			break
+		}
+
+		graph.MostBroken = append(graph.MostBroken, n.URL)
+	}
+
+	graph.Edges = edges
+	return &graph
+}
+
+// addEdge adds the link from source to target to the giving adjacency set.
+func addEdge(set map[string]map[string]bool, source string, target string) {
+	targets, ok := set[source]
+	if !ok {
+		targets = make(map[string]bool)
+		set[source] = targets
+	}
+
+	targets[target] = true
+}
+
+// edgesBySource sorts edges by their source and then their target.
+type edgesBySource []GraphEdge
+
+func (e edgesBySource) Len() int      { return len(e) }
+func (e edgesBySource) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e edgesBySource) Less(i, j int) bool {
+	if e[i].Source != e[j].Source {
+		return e[i].Source < e[j].Source
+	}
+
+	return e[i].Target < e[j].Target
+}
+
+//==============================================================================
+
+// WriteGraph writes the giving link graph to w in the giving format, DOT,
+// GraphML or JSON.
+func WriteGraph(w io.Writer, g *LinkGraph, format string) error {
+	switch format {
+	case "", FormatDOT:
+		return writeDOT(w, g)
+	case FormatGraphML:
+		return writeGraphML(w, g)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(g)
+	}
+
+	return fmt.Errorf("Invalid graph format[%s]", format)
+}
+
+// writeDOT writes the graph in the DOT language of Graphviz, with the
+// analytics of each page as attributes of its node.
+func writeDOT(w io.Writer, g *LinkGraph) error {
+	fmt.Fprintln(w, "digraph spidy {")
+
+	for _, n := range g.Nodes {
+		attrs := []string{
+			"depth=" + strconv.Itoa(n.Depth),
+			"indegree=" + strconv.Itoa(n.InDegree),
+			"outdegree=" + strconv.Itoa(n.OutDegree),
+			"broken=" + strconv.Itoa(n.Broken),
+		}
+
+		switch {
+		case n.Broken > 0:
+			attrs = append(attrs, "color=red")
+		case n.Orphan:
+			attrs = append(attrs, "style=dashed")
+		}
+
+		fmt.Fprintf(w, "  %s [%s];\n", strconv.Quote(n.URL), strings.Join(attrs, ", "))
+	}
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(w, "  %s -> %s;\n", strconv.Quote(e.Source), strconv.Quote(e.Target))
+	}
+
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// graphMLKeys defines the attributes of the nodes of a graph written as
+// GraphML, as their id and type.
+var graphMLKeys = [][2]string{
+	{"depth", "int"},
+	{"indegree", "int"},
+	{"outdegree", "int"},
+	{"broken", "int"},
+	{"crawled", "boolean"},
+	{"orphan", "boolean"},
+	{"deadend", "boolean"},
+}
+
+// writeGraphML writes the graph as GraphML, with the analytics of each page
+// as the data of its node.
+func writeGraphML(w io.Writer, g *LinkGraph) error {
+	fmt.Fprintln(w, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(w, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+
+	for _, k := range graphMLKeys {
+		fmt.Fprintf(w, "  <key id=%q for=\"node\" attr.name=%q attr.type=%q/>\n", k[0], k[0], k[1])
+	}
+
+	fmt.Fprintln(w, `  <graph id="spidy" edgedefault="directed">`)
+
+	for _, n := range g.Nodes {
+		values := []string{
+			strconv.Itoa(n.Depth),
+			strconv.Itoa(n.InDegree),
+			strconv.Itoa(n.OutDegree),
+			strconv.Itoa(n.Broken),
+			strconv.FormatBool(n.Crawled),
+			strconv.FormatBool(n.Orphan),
+			strconv.FormatBool(n.DeadEnd),
+		}
+
+		fmt.Fprintf(w, "    <node id=\"%s\">\n", escapeXML(n.URL))
+
+		for i, k := range graphMLKeys {
+			fmt.Fprintf(w, "      <data key=%q>%s</data>\n", k[0], values[i])
+		}
+
+		fmt.Fprintln(w, "    </node>")
+	}
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(w, "    <edge source=\"%s\" target=\"%s\"/>\n", escapeXML(e.Source), escapeXML(e.Target))
+	}
+
+	fmt.Fprintln(w, "  </graph>")
+
+	_, err := fmt.Fprintln(w, "</graphml>")
+	return err
+}
+
+// escapeXML returns the giving text escaped for use within XML.
+func escapeXML(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
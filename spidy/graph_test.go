@@ -0,0 +1,194 @@
+package spidy_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+// TestGraph tests recording the internal link graph of a site and its
+// analytics.
+func TestGraph(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to export the link graph of a site")
+	{
+		var server *httptest.Server
+
+		pages := map[string]string{
+			"/":       `<a href="/a">A</a><a href="/b">B</a>`,
+			"/a":      `<a href="/c">C</a><a href="/missing">Missing</a><a href="/gone">Gone</a><img src="/broken.png">`,
+			"/b":      `<a href="/">Home</a><a href="/missing">Missing</a>`,
+			"/c":      `<p>Nowhere to go</p>`,
+			"/lonely": `<a href="/">Home</a>`,
+		}
+
+		server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/sitemap.xml" {
+				fmt.Fprintf(res, `<urlset><url><loc>%s/c</loc></url><url><loc>%s/lonely</loc></url></urlset>`, server.URL, server.URL)
+				return
+			}
+
+			body, ok := pages[req.URL.Path]
+			if !ok {
+				http.NotFound(res, req)
+				return
+			}
+
+			res.Header().Set("Content-Type", "text/html")
+			res.Write([]byte("<html><body>" + body + "</body></html>"))
+		}))
+
+		defer server.Close()
+
+		conf := spidy.Config{
+			Client:  &http.Client{Timeout: 30 * time.Second},
+			URL:     server.URL,
+			Workers: 10,
+			Depth:   -1,
+			Events:  events,
+			Graph:   true,
+		}
+
+		report, err := spidy.Crawl(context, &conf)
+		if err != nil {
+			t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+		}
+
+		graph := report.Graph
+		if graph == nil {
+			t.Fatalf("\t%s\tShould have recorded the link graph", tests.Failed)
+		}
+
+		short := func(pages []string) string {
+			var out []string
+			for _, page := range pages {
+				out = append(out, strings.TrimPrefix(page, server.URL))
+			}
+
+			return strings.Join(out, ",")
+		}
+
+		t.Logf("\tWhen crawling a site with the link graph recorded")
+		{
+			var found []string
+			for _, n := range graph.Nodes {
+				found = append(found, fmt.Sprintf("%s:%d:%d:%d:%d", strings.TrimPrefix(n.URL, server.URL), n.Depth, n.InDegree, n.OutDegree, n.Broken))
+			}
+
+			expected := "/:0:1:2:0,/a:1:1:3:3,/b:1:1:2:1,/c:2:1:0:0,/gone:2:1:0:0,/lonely:-1:0:0:0,/missing:2:2:0:0"
+			if strings.Join(found, ",") != expected {
+				t.Errorf("\t%s\tShould have measured the depth, degrees and broken links of each page:\n%s\n\tbut got:\n%s", tests.Failed, expected, strings.Join(found, ","))
+			} else {
+				t.Logf("\t%s\tShould have measured the depth, degrees and broken links of each page", tests.Success)
+			}
+
+			if len(graph.Edges) != 7 {
+				t.Errorf("\t%s\tShould have recorded the hyperlinks between pages but got %v", tests.Failed, graph.Edges)
+			} else {
+				t.Logf("\t%s\tShould have recorded the hyperlinks between pages", tests.Success)
+			}
+
+			if got := short(graph.Orphans); got != "/lonely" {
+				t.Errorf("\t%s\tShould have found the pages of the sitemap which are not linked to but got %s", tests.Failed, got)
+			} else {
+				t.Logf("\t%s\tShould have found the pages of the sitemap which are not linked to", tests.Success)
+			}
+
+			if got := short(graph.DeadEnds); got != "/c" {
+				t.Errorf("\t%s\tShould have found the pages which link nowhere but got %s", tests.Failed, got)
+			} else {
+				t.Logf("\t%s\tShould have found the pages which link nowhere", tests.Success)
+			}
+
+			if got := short(graph.MostBroken); got != "/a,/b" {
+				t.Errorf("\t%s\tShould have ranked the pages by their broken links but got %s", tests.Failed, got)
+			} else {
+				t.Logf("\t%s\tShould have ranked the pages by their broken links", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen writing the link graph")
+		{
+			var dot, graphml, js bytes.Buffer
+
+			if err := spidy.WriteGraph(&dot, graph, spidy.FormatDOT); err != nil {
+				t.Fatalf("\t%s\tShould have written the graph as DOT: %q", tests.Failed, err)
+			}
+
+			if !strings.Contains(dot.String(), fmt.Sprintf("%q -> %q;", server.URL+"/a", server.URL+"/c")) {
+				t.Errorf("\t%s\tShould have written the edges as DOT but got:\n%s", tests.Failed, dot.String())
+			} else {
+				t.Logf("\t%s\tShould have written the edges as DOT", tests.Success)
+			}
+
+			if err := spidy.WriteGraph(&graphml, graph, spidy.FormatGraphML); err != nil {
+				t.Fatalf("\t%s\tShould have written the graph as GraphML: %q", tests.Failed, err)
+			}
+
+			if !strings.Contains(graphml.String(), fmt.Sprintf(`<edge source="%s/a" target="%s/c"/>`, server.URL, server.URL)) {
+				t.Errorf("\t%s\tShould have written the edges as GraphML but got:\n%s", tests.Failed, graphml.String())
+			} else {
+				t.Logf("\t%s\tShould have written the edges as GraphML", tests.Success)
+			}
+
+			if err := spidy.WriteGraph(&js, graph, spidy.FormatJSON); err != nil {
+				t.Fatalf("\t%s\tShould have written the graph as JSON: %q", tests.Failed, err)
+			}
+
+			var decoded spidy.LinkGraph
+			if err := json.Unmarshal(js.Bytes(), &decoded); err != nil || len(decoded.Nodes) != len(graph.Nodes) {
+				t.Errorf("\t%s\tShould have written the graph as JSON: %v", tests.Failed, err)
+			} else {
+				t.Logf("\t%s\tShould have written the graph as JSON", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen crawling the site with a page budget")
+		{
+			conf := conf
+			conf.Budget = spidy.Budget{Pages: 2}
+			conf.SEO = &spidy.SEO{}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have crawled the site", tests.Success)
+
+			if report.Budget.Exhausted != spidy.BudgetPages {
+				t.Errorf("\t%s\tShould have spent the page budget but got %q", tests.Failed, report.Budget.Exhausted)
+			} else {
+				t.Logf("\t%s\tShould have spent the page budget", tests.Success)
+			}
+
+			if report.Graph == nil || short(report.Graph.Orphans) != "/lonely" {
+				t.Errorf("\t%s\tShould have still found the pages of the sitemap which are not linked to", tests.Failed)
+			} else {
+				t.Logf("\t%s\tShould have still found the pages of the sitemap which are not linked to", tests.Success)
+			}
+
+			var orphans []string
+			for _, f := range report.Links {
+				if f.Kind == "seo-"+spidy.SEOOrphan {
+					orphans = append(orphans, f.Link)
+				}
+			}
+
+			if short(orphans) != "/lonely" {
+				t.Errorf("\t%s\tShould have still reported the orphan pages of the sitemap but got %s", tests.Failed, short(orphans))
+			} else {
+				t.Logf("\t%s\tShould have still reported the orphan pages of the sitemap", tests.Success)
+			}
+		}
+	}
+}
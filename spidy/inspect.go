@@ -0,0 +1,106 @@
+package spidy
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Page defines an HTML page fetched by the crawl.
+type Page struct {
+	Link     string            // Link the page was requested as.
+	URL      *url.URL          // Final URL of the page after any redirects.
+	Status   int               // Status of the response.
+	Header   http.Header       // Headers of the response.
+	Document *goquery.Document // Parsed document of the page, nil when the crawl has no inspectors.
+}
+
+// PageInspector defines an interface for custom checks of every HTML page of
+// a crawl. Pages are inspected by the workers of the crawl as they are
+// fetched, so Inspect must be safe to call concurrently and must not modify
+// the document.
+//
+// Findings returned are added to the report, their Link defaults to the URL
+// of the page, their Source to the page which linked to it, their Tag to the
+// element of that link, their Kind to KindInspection and their Severity to
+// SeverityWarning.
+type PageInspector interface {
+	Inspect(context interface{}, page *Page) []LinkReport
+}
+
+// SiteInspector defines an interface for PageInspectors whose checks span the
+// pages of a crawl, such as for duplicate titles. Finish is called once the
+// crawl is done and its findings are added to the report, their Kind and
+// Severity default as with Inspect.
+type SiteInspector interface {
+	PageInspector
+	Finish(context interface{}) []LinkReport
+}
+
+// InspectorFunc defines a function which implements the PageInspector
+// interface.
+type InspectorFunc func(context interface{}, page *Page) []LinkReport
+
+// Inspect implements the PageInspector interface.
+func (f InspectorFunc) Inspect(context interface{}, page *Page) []LinkReport {
+	return f(context, page)
+}
+
+// inspectPage runs each of the inspectors on the giving page, returning their
+// findings with any defaults filled in.
+func inspectPage(context interface{}, inspectors []PageInspector, page *Page) []LinkReport {
+	var findings []LinkReport
+
+	for _, inspector := range inspectors {
+		for _, f := range inspector.Inspect(context, page) {
+			if f.Link == "" {
+				f.Link = page.URL.String()
+			}
+
+			if f.Status == 0 {
+				f.Status = page.Status
+			}
+
+			findings = append(findings, inspected(f))
+		}
+	}
+
+	return findings
+}
+
+// finishSite calls Finish on each of the inspectors which are SiteInspectors,
+// returning their findings with any defaults filled in and attributed to the
+// site of the seeds they are within.
+func finishSite(context interface{}, inspectors []PageInspector, seeds []seed) []LinkReport {
+	var findings []LinkReport
+
+	for _, inspector := range inspectors {
+		si, ok := inspector.(SiteInspector)
+		if !ok {
+			continue
+		}
+
+		for _, f := range si.Finish(context) {
+			f = inspected(f)
+			f.Site = siteOf(seeds, f.Link)
+			findings = append(findings, f)
+		}
+	}
+
+	return findings
+}
+
+// inspected returns the giving finding of an inspector with its Kind and
+// Severity defaulted.
+func inspected(f LinkReport) LinkReport {
+	if f.Kind == "" {
+		f.Kind = KindInspection
+	}
+
+	if f.Severity == "" {
+		f.Severity = SeverityWarning
+	}
+
+	return f
+}
@@ -0,0 +1,122 @@
+package spidy_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// headerInspector records the pages it inspects and flags those without a
+// Cache-Control header.
+type headerInspector struct {
+	ml    sync.Mutex
+	pages []string
+}
+
+// Inspect implements the spidy.PageInspector interface.
+func (h *headerInspector) Inspect(context interface{}, page *spidy.Page) []spidy.LinkReport {
+	h.ml.Lock()
+	h.pages = append(h.pages, page.URL.Path)
+	h.ml.Unlock()
+
+	if page.Header.Get("Cache-Control") != "" {
+		return nil
+	}
+
+	return []spidy.LinkReport{{Kind: "cache-control", Error: fmt.Errorf("Missing Cache-Control")}}
+}
+
+// TestInspectors tests running custom checks on every page of a crawl.
+func TestInspectors(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to run custom checks on every page")
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/":
+				res.Header().Set("Content-Type", "text/html")
+				res.Header().Set("Cache-Control", "max-age=60")
+				res.Write([]byte(`<html><head><link rel="stylesheet" href="/site.css"></head><body><a href="/about">About</a></body></html>`))
+			case "/about":
+				res.Header().Set("Content-Type", "text/html")
+				res.Write([]byte(`<html><body><h1>About</h1><a href="/">Home</a></body></html>`))
+			case "/site.css":
+				res.Header().Set("Content-Type", "text/css")
+				res.Write([]byte(`body { color: black; }`))
+			default:
+				res.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		defer server.Close()
+
+		t.Logf("\tWhen crawling with several inspectors")
+		{
+			headers := headerInspector{}
+
+			headings := spidy.InspectorFunc(func(context interface{}, page *spidy.Page) []spidy.LinkReport {
+				if page.Document.Find("h1").Length() > 0 {
+					return nil
+				}
+
+				return []spidy.LinkReport{{Kind: "heading", Severity: spidy.SeverityError, Error: fmt.Errorf("Missing h1")}}
+			})
+
+			conf := spidy.Config{
+				Client:     &http.Client{Timeout: 30 * time.Second},
+				URL:        server.URL,
+				Workers:    10,
+				Depth:      -1,
+				Events:     events,
+				Inspectors: []spidy.PageInspector{&headers, headings},
+			}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have crawled the site", tests.Success)
+
+			if len(headers.pages) != 2 {
+				t.Errorf("\t%s\tShould have only inspected the 2 HTML pages but got %v", tests.Failed, headers.pages)
+			} else {
+				t.Logf("\t%s\tShould have only inspected the 2 HTML pages", tests.Success)
+			}
+
+			findings := make(map[string]spidy.LinkReport)
+			for _, f := range report.Links {
+				findings[f.Kind] = f
+			}
+
+			cache, ok := findings["cache-control"]
+			if !ok || cache.Link != server.URL+"/about" || cache.Source != server.URL+"/" || cache.Severity != spidy.SeverityWarning || cache.Status != http.StatusOK {
+				t.Errorf("\t%s\tShould have reported the finding with the page's defaults: %+v", tests.Failed, cache)
+			} else {
+				t.Logf("\t%s\tShould have reported the finding with the page's defaults", tests.Success)
+			}
+
+			heading, ok := findings["heading"]
+			if !ok || heading.Link != server.URL+"/" || heading.Severity != spidy.SeverityError {
+				t.Errorf("\t%s\tShould have reported the finding of the second inspector: %+v", tests.Failed, heading)
+			} else {
+				t.Logf("\t%s\tShould have reported the finding of the second inspector", tests.Success)
+			}
+
+			if len(report.Links) != 2 {
+				t.Errorf("\t%s\tShould have reported 2 findings but got %d", tests.Failed, len(report.Links))
+			} else {
+				t.Logf("\t%s\tShould have reported 2 findings", tests.Success)
+			}
+		}
+	}
+}
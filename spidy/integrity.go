@@ -0,0 +1,159 @@
+package spidy
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Types of resources whose content is validated.
+const (
+	resourceScript     = "script"
+	resourceStylesheet = "stylesheet"
+	resourceImage      = "image"
+)
+
+// scriptTypes lists the media types scripts may be served as.
+var scriptTypes = map[string]bool{
+	"application/javascript":   true,
+	"application/x-javascript": true,
+	"application/ecmascript":   true,
+	"text/javascript":          true,
+	"text/ecmascript":          true,
+}
+
+// maxImagePixels is the number of pixels past which images are only validated
+// by their header, as decoding them in full allocates memory for each pixel
+// whatever the size of their body.
+const maxImagePixels = 4096 * 4096
+
+// imageDecoder decodes the header and the whole of an image of a format.
+type imageDecoder struct {
+	config func(io.Reader) (image.Config, error)
+	decode func(io.Reader) (image.Image, error)
+}
+
+// imageDecoders maps the extensions of the image formats which are decoded to
+// their decoders.
+var imageDecoders = map[string]imageDecoder{
+	".png":  {png.DecodeConfig, png.Decode},
+	".jpg":  {jpeg.DecodeConfig, jpeg.Decode},
+	".jpeg": {jpeg.DecodeConfig, jpeg.Decode},
+	".gif":  {gif.DecodeConfig, gif.Decode},
+}
+
+// resourceType returns the type of resource the giving link loads based on the
+// element and attribute it was found in, or an empty string if its content is
+// not validated.
+func resourceType(l pageLink) string {
+	if l.Attr == "@import" {
+		return resourceStylesheet
+	}
+
+	switch l.Element {
+	case "script":
+		return resourceScript
+
+	case "link":
+		if hasRel(l.Rel, "stylesheet") {
+			return resourceStylesheet
+		}
+
+	case "img":
+		return resourceImage
+	}
+
+	return ""
+}
+
+// checkResource fetches the resource at the giving link and validates its
+// content matches the type of resource it is loaded as, returning the status
+// and a non-nil error if it does not.
+func checkResource(link string, resource string, c *Config) (int, error) {
+	res, err := c.Client.Get(link)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	defer res.Body.Close()
+
+	// A resource past its size limit is only validated as far as it was read.
+	lb := limitBody(res, c)
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF && res.ContentLength >= 0 {
+			return res.StatusCode, fmt.Errorf("Body of %d bytes is shorter than its Content-Length of %d", len(body), res.ContentLength)
+		}
+
+		return res.StatusCode, err
+	}
+
+	if len(body) == 0 {
+		return res.StatusCode, fmt.Errorf("Empty %s", resource)
+	}
+
+	if res.ContentLength >= 0 && int64(len(body)) != res.ContentLength && !lb.truncated {
+		return res.StatusCode, fmt.Errorf("Body of %d bytes does not match its Content-Length of %d", len(body), res.ContentLength)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+
+	switch resource {
+	case resourceScript:
+		if !scriptTypes[mediaType] {
+			return res.StatusCode, fmt.Errorf("Script served as %q", mediaType)
+		}
+
+	case resourceStylesheet:
+		if mediaType != "text/css" {
+			return res.StatusCode, fmt.Errorf("Stylesheet served as %q", mediaType)
+		}
+
+	case resourceImage:
+		if !strings.HasPrefix(mediaType, "image/") {
+			return res.StatusCode, fmt.Errorf("Image served as %q", mediaType)
+		}
+
+		// The format is claimed by the extension of the link, not of where
+		// it may redirect to.
+		uri, err := url.Parse(link)
+		if err != nil {
+			break
+		}
+
+		ext := strings.ToLower(path.Ext(uri.Path))
+
+		decoder, ok := imageDecoders[ext]
+		if !ok || lb.truncated {
+			break
+		}
+
+		conf, err := decoder.config(bytes.NewReader(body))
+		if err != nil {
+			return res.StatusCode, fmt.Errorf("Image does not decode as %s : %s", strings.TrimPrefix(ext, "."), err)
+		}
+
+		// The dimensions are those claimed by the header, so are checked
+		// before any pixel is allocated.
+		if conf.Width <= 0 || conf.Height <= 0 || int64(conf.Width)*int64(conf.Height) > maxImagePixels {
+			break
+		}
+
+		if _, err := decoder.decode(bytes.NewReader(body)); err != nil {
+			return res.StatusCode, fmt.Errorf("Image does not decode as %s : %s", strings.TrimPrefix(ext, "."), err)
+		}
+	}
+
+	return res.StatusCode, nil
+}
@@ -0,0 +1,143 @@
+package spidy_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// TestIntegrity tests validating the content of the resources pages load.
+func TestIntegrity(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to validate the resources pages load")
+	{
+		var logo bytes.Buffer
+		if err := png.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+			t.Fatalf("\t%s\tShould be able to encode an image: %q", tests.Failed, err)
+		}
+
+		// The header of the huge image claims a million pixels a side, which
+		// no decode should allocate for.
+		huge := append([]byte(nil), logo.Bytes()...)
+		binary.BigEndian.PutUint32(huge[16:], 1<<20)
+		binary.BigEndian.PutUint32(huge[20:], 1<<20)
+		binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/":
+				res.Header().Set("Content-Type", "text/html")
+				res.Write([]byte(`<html><head>
+					<script src="/app.js"></script>
+					<script src="/error.js"></script>
+					<link rel="stylesheet" href="/site.css">
+				</head><body>
+					<img src="/logo.png">
+					<img src="/login.png">
+					<img src="/corrupt.png">
+					<img src="/huge.png">
+					<img src="/empty.gif">
+					<img src="/short.jpg">
+					<a href="/empty.txt">Not a resource</a>
+				</body></html>`))
+			case "/app.js":
+				res.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+				res.Write([]byte(`console.log("spidy")`))
+			case "/error.js":
+				res.Header().Set("Content-Type", "text/html")
+				res.Write([]byte(`<html><body>Server Error</body></html>`))
+			case "/site.css":
+				res.Header().Set("Content-Type", "text/css")
+				res.Write([]byte(`body { color: black; }`))
+			case "/logo.png":
+				res.Header().Set("Content-Type", "image/png")
+				res.Write(logo.Bytes())
+			case "/login.png":
+				res.Header().Set("Content-Type", "text/html")
+				res.Write([]byte(`<html><body><form>Login</form></body></html>`))
+			case "/corrupt.png":
+				res.Header().Set("Content-Type", "image/png")
+				res.Write([]byte(`not a png`))
+			case "/huge.png":
+				res.Header().Set("Content-Type", "image/png")
+				res.Write(huge)
+			case "/empty.gif":
+				res.Header().Set("Content-Type", "image/gif")
+			case "/short.jpg":
+				res.Header().Set("Content-Type", "image/jpeg")
+				res.Header().Set("Content-Length", "100")
+				res.Write([]byte(`truncated`))
+			case "/empty.txt":
+				res.Header().Set("Content-Type", "text/plain")
+			default:
+				res.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		defer server.Close()
+
+		t.Logf("\tWhen crawling a page with integrity validation")
+		{
+			conf := spidy.Config{
+				Client:    &http.Client{Timeout: 30 * time.Second},
+				URL:       server.URL,
+				Workers:   10,
+				Depth:     -1,
+				Events:    events,
+				Integrity: true,
+			}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the page: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have crawled the page", tests.Success)
+
+			errs := make(map[string]string)
+			for _, f := range report.Links {
+				if f.Kind != spidy.KindIntegrity || f.Severity != spidy.SeverityError {
+					t.Errorf("\t%s\tShould only have found integrity errors but got %s %s for %s", tests.Failed, f.Kind, f.Severity, f.Link)
+					continue
+				}
+
+				errs[strings.TrimPrefix(f.Link, server.URL)] = f.Error.Error()
+			}
+
+			expected := map[string]string{
+				"/error.js":    "Script served as",
+				"/login.png":   "Image served as",
+				"/corrupt.png": "Image does not decode as png",
+				"/empty.gif":   "Empty image",
+				"/short.jpg":   "shorter than its Content-Length",
+			}
+
+			for path, msg := range expected {
+				if !strings.Contains(errs[path], msg) {
+					t.Errorf("\t%s\tShould have flagged %s with %q but got %q", tests.Failed, path, msg, errs[path])
+					continue
+				}
+				t.Logf("\t%s\tShould have flagged %s with %q", tests.Success, path, msg)
+			}
+
+			if len(errs) != len(expected) {
+				t.Errorf("\t%s\tShould have only flagged the broken resources but got %v", tests.Failed, errs)
+			} else {
+				t.Logf("\t%s\tShould have only flagged the broken resources", tests.Success)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package spidy
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Classes of resources loaded by a page, active resources can alter the page
+// and so are more dangerous when loaded insecurely than passive ones.
+const (
+	resourceNone = iota
+	resourcePassive
+	resourceActive
+	resourceLink
+)
+
+// resourceClass returns the class of resource the giving link loads based on
+// the element and attribute it was found in.
+func resourceClass(l pageLink) int {
+	// References from stylesheets load fonts and images, except for imports
+	// which load further stylesheets.
+	switch l.Attr {
+	case "@import":
+		return resourceActive
+	case "url()", "style":
+		return resourcePassive
+	}
+
+	switch l.Element {
+	case "script", "iframe", "frame", "embed", "object":
+		return resourceActive
+
+	case "link":
+		if hasRel(l.Rel, "stylesheet", "preload", "modulepreload", "import") {
+			return resourceActive
+		}
+
+		if hasRel(l.Rel, "icon", "apple-touch-icon", "manifest") {
+			return resourcePassive
+		}
+
+		return resourceLink
+
+	case "img", "audio", "video", "source", "track", "picture", "input":
+		return resourcePassive
+
+	case "a", "area":
+		return resourceLink
+	}
+
+	return resourceNone
+}
+
+// hasRel reports whether the space separated rel attribute contains any of
+// the giving values.
+func hasRel(rel string, values ...string) bool {
+	for _, field := range strings.Fields(strings.ToLower(rel)) {
+		for _, value := range values {
+			if field == value {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+//==============================================================================
+
+// secureProbe checks whether http links have a working https equivalent,
+// remembering the result so each link is only probed once per crawl.
+type secureProbe struct {
+	config *Config
+
+	ml     sync.Mutex
+	probes map[string]bool
+}
+
+// newSecureProbe returns a new secureProbe using the giving config.
+func newSecureProbe(c *Config) *secureProbe {
+	return &secureProbe{
+		config: c,
+		probes: make(map[string]bool),
+	}
+}
+
+// hasSecure reports whether the https equivalent of the giving http link
+// responds successfully.
+func (s *secureProbe) hasSecure(link *url.URL) bool {
+	secure := *link
+	secure.Scheme = "https"
+
+	key := secure.String()
+
+	s.ml.Lock()
+	ok, found := s.probes[key]
+	s.ml.Unlock()
+
+	if found {
+		return ok
+	}
+
+	res, err := s.config.Client.Head(key)
+	if err == nil {
+		res.Body.Close()
+		ok = res.StatusCode >= 200 && res.StatusCode <= 299
+	}
+
+	s.ml.Lock()
+	s.probes[key] = ok
+	s.ml.Unlock()
+
+	return ok
+}
+
+// checkMixed returns the finding for a link of a https page which loads a
+// resource or links to a page over plain http, else returns false.
+func (s *secureProbe) checkMixed(page string, l pageLink) (LinkReport, bool) {
+	if !strings.HasPrefix(page, "https:") {
+		return LinkReport{}, false
+	}
+
+	link, err := url.Parse(strings.TrimSpace(l.URL))
+	if err != nil || link.Scheme != "http" {
+		return LinkReport{}, false
+	}
+
+	report := LinkReport{
+		Link:   link.String(),
+		Source: page,
+		Kind:   KindMixedContent,
+	}
+
+	switch resourceClass(l) {
+	case resourceActive:
+		report.Severity = SeverityError
+		report.Error = fmt.Errorf("Active mixed content : %s loaded over http", l.Element)
+
+	case resourcePassive:
+		report.Severity = SeverityWarning
+		report.Error = fmt.Errorf("Passive mixed content : %s loaded over http", l.Element)
+
+	case resourceLink:
+		if !s.hasSecure(link) {
+			return LinkReport{}, false
+		}
+
+		report.Kind = KindInsecureLink
+		report.Severity = SeverityWarning
+		report.Error = fmt.Errorf("Insecure link : %s is available over https", link)
+
+	default:
+		return LinkReport{}, false
+	}
+
+	return report, true
+}
@@ -0,0 +1,128 @@
+package spidy_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// TestMixedContent tests the detection of resources and links loaded over
+// plain http by https pages.
+func TestMixedContent(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to find mixed content within https pages")
+	{
+		insecure := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.Header().Set("Content-Type", "image/png")
+		}))
+
+		defer insecure.Close()
+
+		var page []byte
+
+		server := httptest.NewTLSServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/" {
+				res.Write(page)
+			}
+		}))
+
+		defer server.Close()
+
+		page = []byte(fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<body>
+		<script src="%[1]s/app.js"></script>
+		<img src="%[1]s/logo.png" />
+		<a href="http://%[2]s/about"></a>
+		<a href="%[1]s/elsewhere"></a>
+</body>
+</html>`, insecure.URL, server.Listener.Addr()))
+
+		caFile := writeCAFile(server, t)
+		defer os.Remove(caFile)
+
+		conf := spidy.Config{
+			Client:  &http.Client{Timeout: 30 * time.Second},
+			URL:     server.URL,
+			Workers: 30,
+			Depth:   -1,
+			Events:  events,
+			CAFile:  caFile,
+		}
+
+		t.Logf("\tWhen crawling a https page with insecure resources and links")
+		{
+			badlinks, err := spidy.Run(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have successfully retrieved page[%s]: %q", tests.Failed, conf.URL, err)
+			}
+			t.Logf("\t%s\tShould have successfully retrieved page[%s]", tests.Success, conf.URL)
+
+			found := make(map[string]spidy.LinkReport)
+			for _, report := range badlinks {
+				if report.Kind == spidy.KindMixedContent || report.Kind == spidy.KindInsecureLink {
+					found[report.Link] = report
+				}
+			}
+
+			if len(found) != 3 {
+				t.Fatalf("\t%s\tShould have found 3 insecure links in page[%s]: %+v", tests.Failed, conf.URL, badlinks)
+			}
+			t.Logf("\t%s\tShould have found 3 insecure links in page[%s]", tests.Success, conf.URL)
+
+			if r := found[insecure.URL+"/app.js"]; r.Kind != spidy.KindMixedContent || r.Severity != spidy.SeverityError {
+				t.Fatalf("\t%s\tShould have reported the script as active mixed content: %+v", tests.Failed, r)
+			}
+			t.Logf("\t%s\tShould have reported the script as active mixed content", tests.Success)
+
+			if r := found[insecure.URL+"/logo.png"]; r.Kind != spidy.KindMixedContent || r.Severity != spidy.SeverityWarning {
+				t.Fatalf("\t%s\tShould have reported the image as passive mixed content: %+v", tests.Failed, r)
+			}
+			t.Logf("\t%s\tShould have reported the image as passive mixed content", tests.Success)
+
+			if r := found[fmt.Sprintf("http://%s/about", server.Listener.Addr())]; r.Kind != spidy.KindInsecureLink || r.Source != server.URL+"/" {
+				t.Fatalf("\t%s\tShould have reported the link available over https: %+v", tests.Failed, r)
+			}
+			t.Logf("\t%s\tShould have reported the link available over https", tests.Success)
+		}
+
+		t.Logf("\tWhen crawling a http URL redirecting to the https page")
+		{
+			redirect := httptest.NewServer(http.RedirectHandler(server.URL+"/", http.StatusMovedPermanently))
+			defer redirect.Close()
+
+			conf := conf
+			conf.URL = redirect.URL
+
+			badlinks, err := spidy.Run(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have successfully retrieved page[%s]: %q", tests.Failed, conf.URL, err)
+			}
+			t.Logf("\t%s\tShould have successfully retrieved page[%s]", tests.Success, conf.URL)
+
+			var found int
+			for _, report := range badlinks {
+				if report.Kind == spidy.KindMixedContent {
+					found++
+				}
+			}
+
+			if found != 2 {
+				t.Errorf("\t%s\tShould have found the mixed content of the page as served but found %d: %+v", tests.Failed, found, badlinks)
+			} else {
+				t.Logf("\t%s\tShould have found the mixed content of the page as served", tests.Success)
+			}
+		}
+	}
+}
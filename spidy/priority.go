@@ -0,0 +1,122 @@
+package spidy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+)
+
+// defaultSitemapPriority is the priority of pages listed within a sitemap
+// without one.
+const defaultSitemapPriority = 0.5
+
+// PriorityFunc scores a page pending within a crawl, pages with higher scores
+// are crawled first. Pages with the same score are crawled shallowest first
+// and then in order of their URL, so crawls of the same site run in the same
+// order.
+type PriorityFunc func(entry FrontierEntry) float64
+
+// DepthPriority scores pages by their depth so they are crawled breadth first,
+// which is the default of a crawl.
+func DepthPriority(entry FrontierEntry) float64 {
+	return -float64(entry.Depth)
+}
+
+// PatternPriority returns a PriorityFunc scoring pages by the sum of the
+// weights of the patterns their URL matches, where patterns are regular
+// expressions such as "/docs/" or "\.pdf$".
+func PatternPriority(weights map[string]float64) (PriorityFunc, error) {
+	type pattern struct {
+		re     *regexp.Regexp
+		weight float64
+	}
+
+	var patterns []pattern
+	for expr, weight := range weights {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid priority pattern[%s] : %s", expr, err)
+		}
+
+		patterns = append(patterns, pattern{re: re, weight: weight})
+	}
+
+	fn := func(entry FrontierEntry) float64 {
+		var score float64
+		for _, p := range patterns {
+			if p.re.MatchString(entry.URL) {
+				score += p.weight
+			}
+		}
+
+		return score
+	}
+
+	return fn, nil
+}
+
+// SitemapPriority returns a PriorityFunc scoring pages by their priority within
+// the sitemap of their host, read through the giving client the first time a
+// page of the host is scored. Pages not listed within the sitemap score 0.
+// Crawls reading sitemaps through their own client set PrioritizeSitemap
+// instead.
+func SitemapPriority(client *http.Client) PriorityFunc {
+	return sitemapPriority(&Config{Client: client})
+}
+
+// sitemapPriority returns the PriorityFunc of SitemapPriority, reading
+// sitemaps with the giving config.
+func sitemapPriority(c *Config) PriorityFunc {
+	type sitemap struct {
+		once       sync.Once
+		priorities map[string]float64
+	}
+
+	var ml sync.Mutex
+	hosts := make(map[string]*sitemap)
+
+	return func(entry FrontierEntry) float64 {
+		uri, err := url.Parse(entry.URL)
+		if err != nil {
+			return 0
+		}
+
+		host := uri.Scheme + "://" + uri.Host
+
+		ml.Lock()
+		sm, ok := hosts[host]
+		if !ok {
+			sm = &sitemap{priorities: make(map[string]float64)}
+			hosts[host] = sm
+		}
+		ml.Unlock()
+
+		sm.once.Do(func() {
+			for _, u := range readSitemap(host, c) {
+				priority := u.Priority
+				if priority <= 0 {
+					priority = defaultSitemapPriority
+				}
+
+				sm.priorities[u.Loc] = priority
+			}
+		})
+
+		return sm.priorities[entry.URL]
+	}
+}
+
+// CombinePriority returns a PriorityFunc scoring pages by the sum of the scores
+// of the giving funcs.
+func CombinePriority(funcs ...PriorityFunc) PriorityFunc {
+	return func(entry FrontierEntry) float64 {
+		var score float64
+		for _, fn := range funcs {
+			score += fn(entry)
+		}
+
+		return score
+	}
+}
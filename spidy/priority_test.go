@@ -0,0 +1,407 @@
+package spidy_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+// TestFrontierPriority tests the order pages pending within a crawl are popped
+// from a memory frontier.
+func TestFrontierPriority(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	entries := []spidy.FrontierEntry{
+		{URL: "/blog/b", Depth: 2},
+		{URL: "/docs/a", Depth: 2},
+		{URL: "/b", Depth: 1},
+		{URL: "/", Depth: 0},
+		{URL: "/docs", Depth: 1},
+		{URL: "/a", Depth: 1},
+	}
+
+	pop := func(f spidy.Frontier) string {
+		for _, entry := range entries {
+			if err := f.Push(entry); err != nil {
+				t.Fatalf("\t%s\tShould be able to push a page: %q", tests.Failed, err)
+			}
+		}
+
+		var popped []string
+		for {
+			entry, ok := f.Pop()
+			if !ok {
+				break
+			}
+
+			popped = append(popped, entry.URL)
+		}
+
+		return strings.Join(popped, ",")
+	}
+
+	t.Logf("Given the need to crawl the most important pages first")
+	{
+		t.Logf("\tWhen popping pages without a priority")
+		{
+			popped := pop(spidy.NewMemoryFrontier(nil))
+
+			if popped != "/,/a,/b,/docs,/blog/b,/docs/a" {
+				t.Errorf("\t%s\tShould have popped the pages breadth first in order of their URL but got %s", tests.Failed, popped)
+			} else {
+				t.Logf("\t%s\tShould have popped the pages breadth first in order of their URL", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen popping pages with a pattern priority")
+		{
+			pattern, err := spidy.PatternPriority(map[string]float64{"^/docs": 10})
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to create a pattern priority: %q", tests.Failed, err)
+			}
+
+			popped := pop(spidy.NewMemoryFrontier(spidy.CombinePriority(spidy.DepthPriority, pattern)))
+
+			if popped != "/docs,/docs/a,/,/a,/b,/blog/b" {
+				t.Errorf("\t%s\tShould have popped the matching pages first but got %s", tests.Failed, popped)
+			} else {
+				t.Logf("\t%s\tShould have popped the matching pages first", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen creating a pattern priority of an invalid pattern")
+		{
+			if _, err := spidy.PatternPriority(map[string]float64{"(": 1}); err == nil {
+				t.Errorf("\t%s\tShould have failed to create the priority", tests.Failed)
+			} else {
+				t.Logf("\t%s\tShould have failed to create the priority", tests.Success)
+			}
+		}
+	}
+}
+
+// TestCrawlOrder tests crawling the pages of a site breadth first, within a
+// maximum depth and by priority.
+func TestCrawlOrder(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to crawl the pages of a site in a reproducible order")
+	{
+		var ml sync.Mutex
+		var fetched []string
+		var heads int
+		var slow string
+
+		// Each page links to three children, four links deep, and the
+		// deepest pages to a missing page. The slow page, if any, takes a
+		// while to load.
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			ml.Lock()
+			delay := req.Method == "GET" && req.URL.Path == slow
+			if req.Method == "HEAD" {
+				heads++
+			}
+			ml.Unlock()
+
+			if delay {
+				time.Sleep(200 * time.Millisecond)
+			}
+
+			if strings.HasPrefix(req.URL.Path, "/missing") {
+				http.NotFound(res, req)
+				return
+			}
+
+			// The sitemap is only served to the client of the crawl.
+			if req.URL.Path == "/sitemap.xml" {
+				if req.Header.Get("X-Spidy-Token") == "" {
+					res.WriteHeader(http.StatusForbidden)
+					return
+				}
+
+				res.Header().Set("Content-Type", "application/xml")
+				fmt.Fprintf(res, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>http://%s/c</loc><priority>1.0</priority></url></urlset>`, req.Host)
+				return
+			}
+
+			if req.Method == "GET" {
+				ml.Lock()
+				fetched = append(fetched, req.URL.Path)
+				ml.Unlock()
+			}
+
+			res.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(res, `<html><body>`)
+
+			path := strings.TrimSuffix(req.URL.Path, "/")
+			if depth := strings.Count(path, "/"); depth < 4 {
+				for _, child := range []string{"a", "b", "c"} {
+					fmt.Fprintf(res, `<a href="%s/%s">Child</a>`, path, child)
+				}
+			} else {
+				fmt.Fprintf(res, `<a href="/missing%s">Missing</a>`, path)
+			}
+
+			fmt.Fprintf(res, `</body></html>`)
+		}))
+
+		defer server.Close()
+
+		crawl := func(depth int, budget spidy.Budget, priority spidy.PriorityFunc) ([]string, int) {
+			ml.Lock()
+			fetched = nil
+			heads = 0
+			ml.Unlock()
+
+			conf := spidy.Config{
+				Client:   &http.Client{Timeout: 30 * time.Second},
+				URL:      server.URL,
+				Workers:  10,
+				Depth:    depth,
+				Events:   events,
+				Budget:   budget,
+				Priority: priority,
+			}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+
+			var dead int
+			for _, f := range report.Links {
+				if f.Kind == spidy.KindDeadLink {
+					dead++
+				}
+			}
+
+			ml.Lock()
+			defer ml.Unlock()
+
+			pages := append([]string(nil), fetched...)
+			sort.Strings(pages)
+			return pages, dead
+		}
+
+		// shallow reports whether all the giving pages are within the giving
+		// depth.
+		shallow := func(pages []string, depth int) bool {
+			for _, page := range pages {
+				if strings.Count(strings.TrimSuffix(page, "/"), "/") > depth {
+					return false
+				}
+			}
+
+			return true
+		}
+
+		t.Logf("\tWhen crawling the site with a budget")
+		{
+			first, _ := crawl(0, spidy.Budget{Pages: 13}, nil)
+			second, _ := crawl(0, spidy.Budget{Pages: 13}, nil)
+
+			if len(first) != 13 || !shallow(first, 2) {
+				t.Errorf("\t%s\tShould have crawled the 13 pages closest to the seed but got %v", tests.Failed, first)
+			} else {
+				t.Logf("\t%s\tShould have crawled the 13 pages closest to the seed", tests.Success)
+			}
+
+			if strings.Join(first, ",") != strings.Join(second, ",") {
+				t.Errorf("\t%s\tShould have crawled the same pages every run but got %v and %v", tests.Failed, first, second)
+			} else {
+				t.Logf("\t%s\tShould have crawled the same pages every run", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen crawling the site with a budget ending within a level")
+		{
+			first, _ := crawl(0, spidy.Budget{Pages: 7}, nil)
+
+			ml.Lock()
+			checked := heads
+			slow = "/a"
+			ml.Unlock()
+
+			second, _ := crawl(0, spidy.Budget{Pages: 7}, nil)
+
+			ml.Lock()
+			slow = ""
+			ml.Unlock()
+
+			expected := "/,/a,/a/a,/a/b,/a/c,/b,/c"
+
+			if strings.Join(first, ",") != expected || strings.Join(second, ",") != expected {
+				t.Errorf("\t%s\tShould have crawled the same best pages whatever the latency of the pages but got %v and %v", tests.Failed, first, second)
+			} else {
+				t.Logf("\t%s\tShould have crawled the same best pages whatever the latency of the pages", tests.Success)
+			}
+
+			// The 21 links of the pages crawled and the seed are checked
+			// once, and the 6 pages crawled past the seed once more before
+			// being fetched, but no page pending past the budget is.
+			if checked != 28 {
+				t.Errorf("\t%s\tShould have only checked the pages within the budget but made %d HEAD requests", tests.Failed, checked)
+			} else {
+				t.Logf("\t%s\tShould have only checked the pages within the budget", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen crawling the site with a budget and a pattern priority")
+		{
+			pattern, err := spidy.PatternPriority(map[string]float64{"^https?://[^/]+/c(/|$)": 10})
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to create a pattern priority: %q", tests.Failed, err)
+			}
+
+			pages, _ := crawl(0, spidy.Budget{Pages: 7}, pattern)
+
+			if strings.Join(pages, ",") != "/,/a,/b,/c,/c/a,/c/b,/c/c" {
+				t.Errorf("\t%s\tShould have crawled the matching pages first but got %v", tests.Failed, pages)
+			} else {
+				t.Logf("\t%s\tShould have crawled the matching pages first", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen crawling the site with a budget and the sitemap priority")
+		{
+			ml.Lock()
+			fetched = nil
+			ml.Unlock()
+
+			transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				req = req.Clone(req.Context())
+				req.Header.Set("X-Spidy-Token", "spidy")
+				return http.DefaultTransport.RoundTrip(req)
+			})
+
+			conf := spidy.Config{
+				Client:            &http.Client{Timeout: 30 * time.Second, Transport: transport},
+				URL:               server.URL,
+				Workers:           10,
+				Events:            events,
+				Budget:            spidy.Budget{Pages: 2},
+				PrioritizeSitemap: true,
+			}
+
+			if _, err := spidy.Crawl(context, &conf); err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+
+			ml.Lock()
+			pages := strings.Join(fetched, ",")
+			ml.Unlock()
+
+			if pages != "/,/c" {
+				t.Errorf("\t%s\tShould have crawled the pages of the sitemap read through the client of the crawl first but got %v", tests.Failed, pages)
+			} else {
+				t.Logf("\t%s\tShould have crawled the pages of the sitemap read through the client of the crawl first", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen crawling the site within a maximum depth")
+		{
+			pages, dead := crawl(2, spidy.Budget{}, nil)
+
+			if len(pages) != 13 || !shallow(pages, 2) {
+				t.Errorf("\t%s\tShould have only crawled the pages within the depth but got %v", tests.Failed, pages)
+			} else {
+				t.Logf("\t%s\tShould have only crawled the pages within the depth", tests.Success)
+			}
+
+			if dead != 0 {
+				t.Errorf("\t%s\tShould not have found the dead links past the depth but got %d", tests.Failed, dead)
+			} else {
+				t.Logf("\t%s\tShould not have found the dead links past the depth", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen crawling the whole site")
+		{
+			pages, dead := crawl(0, spidy.Budget{}, nil)
+
+			if len(pages) != 121 || dead != 81 {
+				t.Errorf("\t%s\tShould have crawled every page and found every dead link but got %d pages and %d dead links", tests.Failed, len(pages), dead)
+			} else {
+				t.Logf("\t%s\tShould have crawled every page and found every dead link", tests.Success)
+			}
+		}
+	}
+}
+
+// TestSlowPages tests that a slow page holds up no more than the worker
+// crawling it.
+func TestSlowPages(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to crawl a site with a slow page")
+	{
+		reached := make(chan struct{})
+		var once sync.Once
+
+		// The slow page answers once the end of the chain of pages next to
+		// it is reached, or gives up after a while.
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.Header().Set("Content-Type", "text/html")
+
+			switch req.URL.Path {
+			case "/":
+				fmt.Fprintf(res, `<html><body><a href="/slow">Slow</a><a href="/chain/0">Chain</a></body></html>`)
+
+			case "/slow":
+				if req.Method == "GET" {
+					select {
+					case <-reached:
+					case <-time.After(5 * time.Second):
+						res.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+				}
+
+				fmt.Fprintf(res, `<html><body>Slow</body></html>`)
+
+			case "/chain/5":
+				once.Do(func() { close(reached) })
+				fmt.Fprintf(res, `<html><body>End</body></html>`)
+
+			default:
+				var n int
+				fmt.Sscanf(req.URL.Path, "/chain/%d", &n)
+				fmt.Fprintf(res, `<html><body><a href="/chain/%d">Next</a></body></html>`, n+1)
+			}
+		}))
+
+		defer server.Close()
+
+		t.Logf("\tWhen crawling the site")
+		{
+			conf := spidy.Config{
+				Client:  &http.Client{Timeout: 30 * time.Second},
+				URL:     server.URL,
+				Workers: 2,
+				Events:  events,
+			}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+
+			if len(report.Links) != 0 {
+				t.Errorf("\t%s\tShould have crawled the other pages while the slow page loaded but got %+v", tests.Failed, report.Links)
+			} else {
+				t.Logf("\t%s\tShould have crawled the other pages while the slow page loaded", tests.Success)
+			}
+		}
+	}
+}
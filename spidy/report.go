@@ -0,0 +1,600 @@
+package spidy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats reports can be written in.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// Report defines the results of a crawl.
+type Report struct {
+	Started    time.Time          `json:"started"`
+	Finished   time.Time          `json:"finished"`
+	Sites      []SiteReport       `json:"sites,omitempty"`
+	Links      []LinkReport       `json:"findings"`
+	Certs      []CertReport       `json:"certificates,omitempty"`
+	Duplicates []DuplicateCluster `json:"duplicates,omitempty"`
+	Graph      *LinkGraph         `json:"graph,omitempty"`
+	Budget     *BudgetReport      `json:"budget,omitempty"`
+}
+
+// SiteReport defines the summary of the findings of a named site.
+type SiteReport struct {
+	Name     string   `json:"name"`
+	Seeds    []string `json:"seeds"`
+	Errors   int      `json:"errors"`
+	Warnings int      `json:"warnings"`
+}
+
+// Failed reports whether the report holds any findings with an error severity.
+func (r *Report) Failed() bool {
+	for _, link := range r.Links {
+		if link.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// summarize adds the summary of every named site of the giving seeds to the
+// report.
+func (r *Report) summarize(seeds []seed) {
+	index := make(map[string]int)
+
+	for _, s := range seeds {
+		if s.site == "" {
+			continue
+		}
+
+		i, ok := index[s.site]
+		if !ok {
+			i = len(r.Sites)
+			index[s.site] = i
+			r.Sites = append(r.Sites, SiteReport{Name: s.site})
+		}
+
+		r.Sites[i].Seeds = append(r.Sites[i].Seeds, s.url.String())
+	}
+
+	for _, link := range r.Links {
+		i, ok := index[link.Site]
+		if !ok {
+			continue
+		}
+
+		switch link.Severity {
+		case SeverityError:
+			r.Sites[i].Errors++
+		case SeverityWarning:
+			r.Sites[i].Warnings++
+		}
+	}
+}
+
+//==============================================================================
+
+// WriteReport writes the giving report to w in the giving format.
+func WriteReport(w io.Writer, r *Report, format string) error {
+	switch format {
+	case "", FormatText:
+		return writeText(w, r)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case FormatCSV:
+		return writeCSV(w, r)
+	}
+
+	return fmt.Errorf("Invalid report format[%s]", format)
+}
+
+// writeText writes the report in a human readable form, with the findings
+// grouped by site.
+func writeText(w io.Writer, r *Report) error {
+	fmt.Fprintln(w, textBanner+"-------------------------------")
+	fmt.Fprintf(w, `
+Start Time: %s
+End Time: %s
+Duration: %s
+`, r.Started, r.Finished, r.Finished.Sub(r.Started))
+
+	if b := r.Budget; b != nil && b.Exhausted != "" {
+		fmt.Fprintf(w, `
+PARTIAL: Crawl stopped at its %s budget
+Pages: %d
+Requests: %d
+Bytes: %d
+Unexplored Links: %d
+
+`, b.Exhausted, b.Pages, b.Requests, b.Bytes, b.Unexplored)
+	}
+
+	if len(r.Certs) > 0 {
+		fmt.Fprintln(w, "--------------------CERTIFICATES----------------------------")
+
+		for _, c := range r.Certs {
+			fmt.Fprintf(w, `
+Host: %s
+Issuer: %s
+Expires: %s
+SAN Match: %t
+Error: %v
+
+`, c.Host, c.Issuer, c.NotAfter.Format(time.RFC1123), c.HostMatch, c.Error)
+		}
+	}
+
+	if len(r.Duplicates) > 0 {
+		fmt.Fprintln(w, "--------------------DUPLICATES------------------------------")
+
+		for _, d := range r.Duplicates {
+			canonical := d.Canonical
+			if canonical == "" {
+				canonical = "(inconsistent)"
+			}
+
+			fmt.Fprintf(w, "\nKind: %s\nSimilarity: %.2f\nCanonical: %s\n", d.Kind, d.Similarity, canonical)
+
+			for _, p := range d.Pages {
+				fmt.Fprintf(w, "  %s -> %s\n", p.URL, p.Canonical)
+			}
+		}
+
+		fmt.Fprintln(w)
+	}
+
+	if r.Graph != nil {
+		writeGraphText(w, r.Graph)
+	}
+
+	for _, site := range r.Sites {
+		fmt.Fprintf(w, "--------------------SITE %s\n\nSeeds: %v\nErrors: %d\nWarnings: %d\n\n", site.Name, site.Seeds, site.Errors, site.Warnings)
+	}
+
+	if len(r.Links) > 0 {
+		fmt.Fprintln(w, textFindings+"--------------------------------")
+
+		links := make([]LinkReport, len(r.Links))
+		copy(links, r.Links)
+		sort.Stable(linksBySite(links))
+
+		for _, f := range links {
+			writeFinding(w, f)
+		}
+	}
+
+	_, err := fmt.Fprintln(w, "------------------------------------------------------------")
+	return err
+}
+
+// writeGraphText writes the analytics of a link graph in a human readable
+// form.
+func writeGraphText(w io.Writer, g *LinkGraph) {
+	fmt.Fprintln(w, "--------------------GRAPH-----------------------------------")
+
+	var depth int
+	for _, n := range g.Nodes {
+		if n.Depth > depth {
+			depth = n.Depth
+		}
+	}
+
+	fmt.Fprintf(w, "\nPages: %d\nLinks: %d\nDeepest Click Depth: %d\n", len(g.Nodes), len(g.Edges), depth)
+
+	lists := []struct {
+		name  string
+		pages []string
+	}{
+		{"Orphans", g.Orphans},
+		{"Dead Ends", g.DeadEnds},
+		{"Most Broken Links", g.MostBroken},
+	}
+
+	for _, l := range lists {
+		if len(l.pages) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "%s:\n", l.name)
+		for _, page := range l.pages {
+			fmt.Fprintf(w, "  %s\n", page)
+		}
+	}
+
+	fmt.Fprintln(w)
+}
+
+// writeFinding writes a finding in a human readable form.
+func writeFinding(w io.Writer, f LinkReport) {
+	if f.Site != "" {
+		fmt.Fprintf(w, "\nSite: %s", f.Site)
+	}
+
+	fmt.Fprintf(w, `
+URL: %s
+Source: %s
+Found In: %s (%s)
+Kind: %s
+Severity: %s
+Status Code: %d
+Error: %v
+`, f.Link, f.Source, f.Tag, f.Reference, f.Kind, f.Severity, f.Status, f.Error)
+
+	if f.Confidence > 0 {
+		fmt.Fprintf(w, "Confidence: %.2f\n", f.Confidence)
+	}
+
+	fmt.Fprintln(w)
+}
+
+// csvHeader defines the columns of reports written as CSV.
+var csvHeader = []string{"site", "link", "source", "tag", "reference", "status", "kind", "severity", "error", "confidence"}
+
+// writeCSV writes the findings of the report as CSV, one finding per row.
+func writeCSV(w io.Writer, r *Report) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, f := range r.Links {
+		if err := cw.Write(csvRow(f)); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvRow returns the columns of a finding written as CSV.
+func csvRow(f LinkReport) []string {
+	var msg string
+	if f.Error != nil {
+		msg = f.Error.Error()
+	}
+
+	var confidence string
+	if f.Confidence > 0 {
+		confidence = strconv.FormatFloat(f.Confidence, 'f', 2, 64)
+	}
+
+	return []string{f.Site, f.Link, f.Source, f.Tag, f.Reference, strconv.Itoa(f.Status), f.Kind, string(f.Severity), msg, confidence}
+}
+
+//==============================================================================
+
+// textTime is the layout the times of a report written as text are in.
+const textTime = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// textBanner starts every report written as text, telling them apart from
+// reports of other formats.
+const textBanner = "--------------------Timelapse"
+
+// textFindings starts the findings of a report written as text.
+const textFindings = "--------------------FINDINGS"
+
+// ReadReport reads a report written by WriteReport in the giving format. A
+// report read from CSV holds only its findings, and one read from text only
+// its times and findings.
+func ReadReport(r io.Reader, format string) (*Report, error) {
+	br := bufio.NewReader(r)
+
+	// Reports written as text are told apart by their banner, whatever
+	// format they are read as.
+	if format == FormatText || isTextReport(br) {
+		return readText(br)
+	}
+
+	switch format {
+	case FormatJSON:
+		var report Report
+		if err := json.NewDecoder(br).Decode(&report); err != nil {
+			return nil, fmt.Errorf("Invalid JSON report : %s", err)
+		}
+
+		return &report, nil
+
+	case FormatCSV:
+		return readCSV(br)
+	}
+
+	return nil, fmt.Errorf("Invalid report format[%s] : Expected text, json or csv", format)
+}
+
+// isTextReport reports whether the report being read starts with the banner
+// of a report written as text.
+func isTextReport(br *bufio.Reader) bool {
+	head, _ := br.Peek(len(textBanner))
+	return bytes.Equal(head, []byte(textBanner))
+}
+
+// readText reads the times and findings of a report written as text, whose
+// findings are blocks of Key: value lines as written by writeFinding.
+func readText(r io.Reader) (*Report, error) {
+	var report Report
+	var finding *LinkReport
+	var findings bool
+
+	flush := func() {
+		if finding != nil && finding.Link != "" {
+			report.Links = append(report.Links, *finding)
+		}
+
+		finding = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Banners start each section, the findings being the last.
+		if strings.HasPrefix(line, "----------") {
+			flush()
+			findings = strings.HasPrefix(line, textFindings)
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, ": ")
+
+		if !findings {
+			switch key {
+			case "Start Time":
+				report.Started, _ = time.Parse(textTime, value)
+			case "End Time":
+				report.Finished, _ = time.Parse(textTime, value)
+			}
+
+			continue
+		}
+
+		if line == "" {
+			flush()
+			continue
+		}
+
+		if finding == nil {
+			finding = &LinkReport{}
+		}
+
+		switch key {
+		case "Site":
+			finding.Site = value
+		case "URL":
+			finding.Link = value
+		case "Source":
+			finding.Source = value
+		case "Found In":
+			if i := strings.LastIndex(value, " ("); i >= 0 {
+				finding.Tag = value[:i]
+				finding.Reference = strings.TrimSuffix(value[i+2:], ")")
+			}
+		case "Kind":
+			finding.Kind = value
+		case "Severity":
+			finding.Severity = Severity(value)
+		case "Status Code":
+			finding.Status, _ = strconv.Atoi(value)
+		case "Error":
+			if value != "<nil>" {
+				finding.Error = errors.New(value)
+			}
+		case "Confidence":
+			finding.Confidence, _ = strconv.ParseFloat(value, 64)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Invalid text report : %s", err)
+	}
+
+	flush()
+
+	return &report, nil
+}
+
+// readCSV reads the findings of a report written as CSV, matching the columns
+// by the names within its header.
+func readCSV(r io.Reader) (*Report, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("Invalid CSV report : Missing header")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[name] = i
+	}
+
+	if _, ok := columns["link"]; !ok {
+		return nil, errors.New("Invalid CSV report : Missing link column")
+	}
+
+	column := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+
+		return row[i]
+	}
+
+	var report Report
+
+	for _, row := range rows[1:] {
+		f := LinkReport{
+			Site:      column(row, "site"),
+			Link:      column(row, "link"),
+			Source:    column(row, "source"),
+			Tag:       column(row, "tag"),
+			Reference: column(row, "reference"),
+			Kind:      column(row, "kind"),
+			Severity:  Severity(column(row, "severity")),
+		}
+
+		f.Status, _ = strconv.Atoi(column(row, "status"))
+		f.Confidence, _ = strconv.ParseFloat(column(row, "confidence"), 64)
+
+		if msg := column(row, "error"); msg != "" {
+			f.Error = errors.New(msg)
+		}
+
+		report.Links = append(report.Links, f)
+	}
+
+	return &report, nil
+}
+
+//==============================================================================
+
+// linkJSON defines the JSON form of a LinkReport.
+type linkJSON struct {
+	Link       string   `json:"link"`
+	Source     string   `json:"source,omitempty"`
+	Site       string   `json:"site,omitempty"`
+	Tag        string   `json:"tag,omitempty"`
+	Reference  string   `json:"reference,omitempty"`
+	Status     int      `json:"status"`
+	Error      string   `json:"error,omitempty"`
+	Kind       string   `json:"kind"`
+	Severity   Severity `json:"severity"`
+	Confidence float64  `json:"confidence,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (l LinkReport) MarshalJSON() ([]byte, error) {
+	lj := linkJSON{
+		Link:       l.Link,
+		Source:     l.Source,
+		Site:       l.Site,
+		Tag:        l.Tag,
+		Reference:  l.Reference,
+		Status:     l.Status,
+		Kind:       l.Kind,
+		Severity:   l.Severity,
+		Confidence: l.Confidence,
+	}
+
+	if l.Error != nil {
+		lj.Error = l.Error.Error()
+	}
+
+	return json.Marshal(lj)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (l *LinkReport) UnmarshalJSON(data []byte) error {
+	var lj linkJSON
+	if err := json.Unmarshal(data, &lj); err != nil {
+		return err
+	}
+
+	*l = LinkReport{
+		Link:       lj.Link,
+		Source:     lj.Source,
+		Site:       lj.Site,
+		Tag:        lj.Tag,
+		Reference:  lj.Reference,
+		Status:     lj.Status,
+		Kind:       lj.Kind,
+		Severity:   lj.Severity,
+		Confidence: lj.Confidence,
+	}
+
+	if lj.Error != "" {
+		l.Error = errors.New(lj.Error)
+	}
+
+	return nil
+}
+
+// certJSON defines the JSON form of a CertReport.
+type certJSON struct {
+	Host      string    `json:"host"`
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	DNSNames  []string  `json:"dnsNames"`
+	HostMatch bool      `json:"hostMatch"`
+	Insecure  bool      `json:"insecure"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (c CertReport) MarshalJSON() ([]byte, error) {
+	cj := certJSON{
+		Host:      c.Host,
+		Issuer:    c.Issuer,
+		Subject:   c.Subject,
+		NotBefore: c.NotBefore,
+		NotAfter:  c.NotAfter,
+		DNSNames:  c.DNSNames,
+		HostMatch: c.HostMatch,
+		Insecure:  c.Insecure,
+	}
+
+	if c.Error != nil {
+		cj.Error = c.Error.Error()
+	}
+
+	return json.Marshal(cj)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (c *CertReport) UnmarshalJSON(data []byte) error {
+	var cj certJSON
+	if err := json.Unmarshal(data, &cj); err != nil {
+		return err
+	}
+
+	*c = CertReport{
+		Host:      cj.Host,
+		Issuer:    cj.Issuer,
+		Subject:   cj.Subject,
+		NotBefore: cj.NotBefore,
+		NotAfter:  cj.NotAfter,
+		DNSNames:  cj.DNSNames,
+		HostMatch: cj.HostMatch,
+		Insecure:  cj.Insecure,
+	}
+
+	if cj.Error != "" {
+		c.Error = errors.New(cj.Error)
+	}
+
+	return nil
+}
+
+//==============================================================================
+
+// linksBySite sorts findings by their site.
+type linksBySite []LinkReport
+
+func (l linksBySite) Len() int           { return len(l) }
+func (l linksBySite) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l linksBySite) Less(i, j int) bool { return l[i].Site < l[j].Site }
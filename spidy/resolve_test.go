@@ -0,0 +1,77 @@
+package spidy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// resolvePages provides pages with links relative to themselves, to a page
+// reached through a redirect and to a <base href>.
+var resolvePages = map[string]string{
+	"/":              `<html><body><a href="docs/guide/">Guide</a><a href="/old">Old</a><a href="/based/">Based</a></body></html>`,
+	"/docs/guide/":   `<html><body><img src="img.png"><a href="../other/#top">Other</a></body></html>`,
+	"/docs/other/":   `<html><body><img src="other.png"></body></html>`,
+	"/docs/renamed/": `<html><body><img src="missing.png"></body></html>`,
+	"/based/":        `<html><head><base href="/assets/"></head><body><img src="logo.png"></body></html>`,
+}
+
+// TestResolve tests links are resolved against the page they were found in.
+func TestResolve(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to resolve relative links")
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if page, ok := resolvePages[req.URL.Path]; ok {
+				res.Write([]byte(page))
+				return
+			}
+
+			switch req.URL.Path {
+			case "/old":
+				http.Redirect(res, req, "/docs/renamed/", http.StatusMovedPermanently)
+			case "/docs/guide/img.png", "/docs/other/other.png", "/assets/logo.png":
+				res.Header().Set("Content-Type", "image/png")
+			default:
+				res.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		defer server.Close()
+
+		conf := spidy.Config{
+			Client:  &http.Client{Timeout: 30 * time.Second},
+			URL:     server.URL,
+			Workers: 30,
+			Depth:   -1,
+			Events:  events,
+		}
+
+		t.Logf("\tWhen crawling pages with relative links")
+		{
+			badlinks, err := spidy.Run(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have successfully retrieved page[%s]: %q", tests.Failed, conf.URL, err)
+			}
+			t.Logf("\t%s\tShould have successfully retrieved page[%s]", tests.Success, conf.URL)
+
+			if len(badlinks) != 1 || badlinks[0].Link != server.URL+"/docs/renamed/missing.png" {
+				t.Fatalf("\t%s\tShould have only found the dead link relative to the redirected page: %+v", tests.Failed, badlinks)
+			}
+			t.Logf("\t%s\tShould have only found the dead link relative to the redirected page", tests.Success)
+
+			if badlinks[0].Source != server.URL+"/old" {
+				t.Fatalf("\t%s\tShould have reported the page the link was found on: %+v", tests.Failed, badlinks[0])
+			}
+			t.Logf("\t%s\tShould have reported the page the link was found on", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package spidy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule defines an interface for when a watched crawl runs. Next returns
+// the time of the first run after the giving time, or the zero time if there
+// is none.
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// Every returns a Schedule running every giving interval.
+func Every(interval time.Duration) Schedule {
+	return every(interval)
+}
+
+// every implements the Schedule interface for a fixed interval.
+type every time.Duration
+
+// Next implements the Schedule interface.
+func (e every) Next(after time.Time) time.Time {
+	if e <= 0 {
+		return time.Time{}
+	}
+
+	return after.Add(time.Duration(e))
+}
+
+//==============================================================================
+
+// cronShortcuts maps the shortcuts of cron expressions to their expression.
+var cronShortcuts = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// cron implements the Schedule interface for a cron expression, with the
+// allowed values of each field set as bits.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+
+	// anyDom and anyDow are set if the day of the month or of the week is
+	// not restricted. Days match either if both are.
+	anyDom, anyDow bool
+}
+
+// ParseCron returns the Schedule of the giving cron expression of five fields:
+// minute, hour, day of the month, month and day of the week, where Sunday is
+// 0 or 7. Fields hold a *, values, ranges such as 1-5 and steps such as */15
+// separated by commas. The shortcuts @hourly, @daily, @weekly, @monthly and
+// @yearly are also accepted. Times are in the local time zone.
+func ParseCron(expr string) (Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if shortcut, ok := cronShortcuts[spec]; ok {
+		spec = shortcut
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Invalid cron expression[%s] : Expected 5 fields", expr)
+	}
+
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+	var bits [5]uint64
+	for i, field := range fields {
+		b, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid cron expression[%s] : %s", expr, err)
+		}
+
+		bits[i] = b
+	}
+
+	// Sunday is both 0 and 7.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	c := cron{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		anyDom: fields[2] == "*",
+		anyDow: fields[4] == "*",
+	}
+
+	return &c, nil
+}
+
+// parseCronField returns the bits of the values the giving field of a cron
+// expression allows, within the giving bounds.
+func parseCronField(field string, min int, max int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("Invalid step[%s]", part)
+			}
+
+			step, part = n, part[:i]
+		}
+
+		from, to := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("Invalid range[%s]", part)
+			}
+
+			if to, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, fmt.Errorf("Invalid range[%s]", part)
+			}
+
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("Invalid value[%s]", part)
+			}
+
+			from, to = n, n
+		}
+
+		if from < min || to > max || from > to {
+			return 0, fmt.Errorf("Value out of range[%s] : Expected %d-%d", part, min, max)
+		}
+
+		for n := from; n <= to; n += step {
+			bits |= 1 << uint(n)
+		}
+	}
+
+	return bits, nil
+}
+
+// Next implements the Schedule interface. Runs are looked for up to five
+// years ahead, as far as any date of an expression such as February 29th can
+// be.
+func (c *cron) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !c.day(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// day reports whether the day of the giving time matches the expression.
+func (c *cron) day(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	switch {
+	case c.anyDom && c.anyDow:
+		return true
+	case c.anyDom:
+		return dow
+	case c.anyDow:
+		return dom
+	}
+
+	return dom || dow
+}
+
+//==============================================================================
+
+// Window defines a daily span of time crawls are confined to, as offsets
+// from midnight in the local time zone. A window whose end is before its start
+// spans midnight.
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseWindow returns the Window of the giving span, such as 22:00-06:00.
+func ParseWindow(span string) (Window, error) {
+	bounds := strings.SplitN(strings.TrimSpace(span), "-", 2)
+	if len(bounds) != 2 {
+		return Window{}, fmt.Errorf("Invalid window[%s] : Expected HH:MM-HH:MM", span)
+	}
+
+	var offsets [2]time.Duration
+	for i, bound := range bounds {
+		t, err := time.Parse("15:04", strings.TrimSpace(bound))
+		if err != nil {
+			return Window{}, fmt.Errorf("Invalid window[%s] : Expected HH:MM-HH:MM", span)
+		}
+
+		offsets[i] = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+
+	return Window{Start: offsets[0], End: offsets[1]}, nil
+}
+
+// Contains reports whether the giving time is within the window.
+func (w Window) Contains(t time.Time) bool {
+	offset := t.Sub(midnight(t))
+
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+
+	return offset >= w.Start || offset < w.End
+}
+
+// open returns the first time the window opens at or after the giving time.
+func (w Window) open(t time.Time) time.Time {
+	start := midnight(t).Add(w.Start)
+	if start.Before(t) {
+		start = midnight(t.AddDate(0, 0, 1)).Add(w.Start)
+	}
+
+	return start
+}
+
+// midnight returns the start of the day of the giving time.
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// nextRun returns the time of the first run of the giving schedule after the
+// giving time, confined to the giving windows.
+func nextRun(s Schedule, windows []Window, after time.Time) time.Time {
+	next := s.Next(after)
+	if next.IsZero() {
+		return next
+	}
+
+	return confine(windows, next)
+}
+
+// confine returns the giving time if it's within any of the giving windows,
+// else the time the first of them opens. Times are not confined without any
+// window.
+func confine(windows []Window, t time.Time) time.Time {
+	if len(windows) == 0 {
+		return t
+	}
+
+	var open time.Time
+	for _, w := range windows {
+		if w.Contains(t) {
+			return t
+		}
+
+		if o := w.open(t); open.IsZero() || o.Before(open) {
+			open = o
+		}
+	}
+
+	return open
+}
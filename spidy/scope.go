@@ -0,0 +1,103 @@
+package spidy
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Modes of scope which decide which links are internal to a crawl.
+const (
+	ScopeHost       = "host"       // Links on the exact host of the seed.
+	ScopeSubdomains = "subdomains" // Links on the host of the seed or its subdomains.
+	ScopeDomain     = "domain"     // Links within the registrable domain (eTLD+1) of the seed.
+	ScopePath       = "path"       // Links on the host of the seed under a path prefix.
+	ScopeHosts      = "hosts"      // Links on the host of the seed or an allowed host.
+)
+
+// Scope defines which links are internal to a crawl. Internal pages are
+// crawled while external links are only checked, never recursed into.
+type Scope struct {
+	Mode   string   `json:"mode"`   // One of the Scope modes, defaults to ScopeHost.
+	Hosts  []string `json:"hosts"`  // Host patterns (e.g *.example.org) allowed by ScopeHosts.
+	Prefix string   `json:"prefix"` // Path prefix for ScopePath, defaults to the seed's directory.
+}
+
+// validate returns a non-nil error if the scope is invalid.
+func (s Scope) validate() error {
+	switch s.Mode {
+	case "", ScopeHost, ScopeSubdomains, ScopeDomain, ScopePath, ScopeHosts:
+		return nil
+	}
+
+	return fmt.Errorf("Invalid scope mode[%s]", s.Mode)
+}
+
+// contains reports whether the giving link is internal to a crawl of the seed.
+func (s Scope) contains(seed *url.URL, link *url.URL) bool {
+	seedHost := strings.ToLower(seed.Hostname())
+	linkHost := strings.ToLower(link.Hostname())
+
+	switch s.Mode {
+	case ScopeSubdomains:
+		return linkHost == seedHost || strings.HasSuffix(linkHost, "."+seedHost)
+
+	case ScopeDomain:
+		return registrableDomain(linkHost) == registrableDomain(seedHost)
+
+	case ScopePath:
+		if !strings.EqualFold(link.Host, seed.Host) {
+			return false
+		}
+
+		prefix := s.Prefix
+		if prefix == "" {
+			prefix = seed.Path
+			if !strings.HasSuffix(prefix, "/") {
+				prefix = path.Dir(prefix) + "/"
+			}
+		}
+
+		return strings.HasPrefix(link.Path, prefix) || link.Path+"/" == prefix
+
+	case ScopeHosts:
+		if strings.EqualFold(link.Host, seed.Host) {
+			return true
+		}
+
+		for _, pattern := range s.Hosts {
+			if ok, _ := path.Match(strings.ToLower(pattern), linkHost); ok {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return strings.EqualFold(link.Host, seed.Host)
+}
+
+//==============================================================================
+
+// registrableDomain returns the registrable domain (eTLD+1) of the giving
+// host, which is its public suffix within the public suffix list and the label
+// before it. IP addresses and hosts without a registrable domain are returned
+// as is.
+func registrableDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if net.ParseIP(host) != nil {
+		return host
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+
+	return domain
+}
@@ -0,0 +1,143 @@
+package spidy_test
+
+import (
+	gocontext "context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// scopePage provides a page linking within and beyond the seed's scope.
+var scopePage = []byte(`
+<!DOCTYPE html>
+<html>
+<body>
+	<a href="http://example.test/docs/a">A</a>
+	<a href="http://example.test/blog/">Blog</a>
+	<a href="http://docs.example.test/">Docs</a>
+	<a href="http://evil-example.test/">Evil</a>
+	<a href="http://other.test/">Other</a>
+	<a href="http://www.shop.co.th/">Shop</a>
+	<a href="http://evil.co.th/">Evil Shop</a>
+</body>
+</html>`)
+
+// TestScope tests only the pages within the scope of a crawl are crawled.
+func TestScope(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to limit the crawl to a scope")
+	{
+		var ml sync.Mutex
+		crawled := make(map[string]bool)
+
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if req.Method == "GET" {
+				ml.Lock()
+				crawled[req.Host+req.URL.Path] = true
+				ml.Unlock()
+			}
+
+			res.Write(scopePage)
+		}))
+
+		defer server.Close()
+
+		// Every host is served by the test server.
+		client := http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+					return net.Dial(network, server.Listener.Addr().String())
+				},
+			},
+		}
+
+		scopes := []struct {
+			scope    spidy.Scope
+			url      string
+			expected []string
+		}{
+			{spidy.Scope{}, "", []string{"example.test/blog/", "example.test/docs/", "example.test/docs/a"}},
+			{spidy.Scope{Mode: spidy.ScopePath}, "", []string{"example.test/docs/", "example.test/docs/a"}},
+			{spidy.Scope{Mode: spidy.ScopeSubdomains}, "", []string{"docs.example.test/", "example.test/blog/", "example.test/docs/", "example.test/docs/a"}},
+			{spidy.Scope{Mode: spidy.ScopeDomain}, "", []string{"docs.example.test/", "example.test/blog/", "example.test/docs/", "example.test/docs/a"}},
+			{spidy.Scope{Mode: spidy.ScopeHosts, Hosts: []string{"*.test"}}, "", []string{"docs.example.test/", "evil-example.test/", "example.test/blog/", "example.test/docs/", "example.test/docs/a", "other.test/"}},
+
+			// Domains registered under a public suffix of several labels
+			// are not internal to each other.
+			{spidy.Scope{Mode: spidy.ScopeDomain}, "http://shop.co.th/", []string{"shop.co.th/", "www.shop.co.th/"}},
+		}
+
+		for _, sc := range scopes {
+			if sc.url == "" {
+				sc.url = "http://example.test/docs/"
+			}
+
+			t.Logf("\tWhen crawling %s with scope[%+v]", sc.url, sc.scope)
+			{
+				crawled = make(map[string]bool)
+
+				conf := spidy.Config{
+					Client:  &client,
+					URL:     sc.url,
+					All:     true,
+					Workers: 30,
+					Depth:   -1,
+					Events:  events,
+					Scope:   sc.scope,
+				}
+
+				badlinks, err := spidy.Run(context, &conf)
+				if err != nil {
+					t.Fatalf("\t%s\tShould have successfully retrieved page[%s]: %q", tests.Failed, conf.URL, err)
+				}
+				t.Logf("\t%s\tShould have successfully retrieved page[%s]", tests.Success, conf.URL)
+
+				if len(badlinks) > 0 {
+					t.Fatalf("\t%s\tShould have found no deadlinks: %+v", tests.Failed, badlinks)
+				}
+				t.Logf("\t%s\tShould have found no deadlinks", tests.Success)
+
+				var pages []string
+				for page := range crawled {
+					pages = append(pages, page)
+				}
+
+				sort.Strings(pages)
+
+				if strings.Join(pages, " ") != strings.Join(sc.expected, " ") {
+					t.Fatalf("\t%s\tShould have crawled pages %v: %v", tests.Failed, sc.expected, pages)
+				}
+				t.Logf("\t%s\tShould have crawled pages %v", tests.Success, sc.expected)
+			}
+		}
+
+		t.Logf("\tWhen crawling with an invalid scope")
+		{
+			conf := spidy.Config{
+				Client:  &client,
+				URL:     "http://example.test/docs/",
+				Workers: 30,
+				Events:  events,
+				Scope:   spidy.Scope{Mode: "internet"},
+			}
+
+			if _, err := spidy.Run(context, &conf); err == nil {
+				t.Fatalf("\t%s\tShould have failed with an invalid scope", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have failed with an invalid scope", tests.Success)
+		}
+	}
+}
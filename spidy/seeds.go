@@ -0,0 +1,157 @@
+package spidy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// Seed defines a URL a crawl starts from, with the scope of the pages crawled
+// from it and the name of the site it belongs to.
+type Seed struct {
+	URL   string `json:"url"`
+	Scope Scope  `json:"scope"`
+	Site  string `json:"site,omitempty"`
+}
+
+// seed defines a parsed Seed.
+type seed struct {
+	url   *url.URL
+	scope Scope
+	site  string
+}
+
+// seedsOf returns the parsed seeds of the giving config, which are the URL of
+// the config with its scope followed by any of its Seeds.
+func seedsOf(c *Config) ([]seed, error) {
+	all := c.Seeds
+	if c.URL != "" {
+		all = append([]Seed{{URL: c.URL, Scope: c.Scope}}, all...)
+	}
+
+	if len(all) == 0 {
+		return nil, errors.New("No URL to crawl")
+	}
+
+	var seeds []seed
+	for _, s := range all {
+		path, err := url.Parse(s.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.Scope.validate(); err != nil {
+			return nil, err
+		}
+
+		// Links to the root of a host always have a path, so the seed is
+		// marked as visited under the same URL.
+		if path.IsAbs() && path.Path == "" {
+			path.Path = "/"
+		}
+
+		seeds = append(seeds, seed{url: path, scope: s.Scope, site: s.Site})
+	}
+
+	return seeds, nil
+}
+
+// inScope reports whether the giving link is internal to the scope of any of
+// the seeds.
+func inScope(seeds []seed, link *url.URL) bool {
+	for _, s := range seeds {
+		if s.scope.contains(s.url, link) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// siteOf returns the name of the site of the first seed whose scope the
+// giving link is internal to, if any.
+func siteOf(seeds []seed, link string) string {
+	uri, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+
+	for _, s := range seeds {
+		if s.scope.contains(s.url, uri) {
+			return s.site
+		}
+	}
+
+	return ""
+}
+
+//==============================================================================
+
+// Site defines a named site crawled as part of a manifest.
+type Site struct {
+	Name  string   `json:"name"`
+	Seeds []string `json:"seeds"`
+	Scope Scope    `json:"scope"`
+}
+
+// Manifest defines several named sites which are crawled together, sharing
+// the checks of the links between them.
+type Manifest struct {
+	Sites []Site `json:"sites"`
+}
+
+// LoadManifest reads the JSON manifest at the giving path.
+//
+//	{
+//	  "sites": [
+//	    {"name": "docs", "seeds": ["https://docs.example.com/"], "scope": {"mode": "path"}},
+//	    {"name": "blog", "seeds": ["https://example.com/blog/"], "scope": {"mode": "subdomains"}}
+//	  ]
+//	}
+func LoadManifest(path string) (*Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var m Manifest
+	if err := json.NewDecoder(file).Decode(&m); err != nil {
+		return nil, fmt.Errorf("Invalid manifest[%s] : %s", path, err)
+	}
+
+	if len(m.Sites) == 0 {
+		return nil, fmt.Errorf("Invalid manifest[%s] : No sites defined", path)
+	}
+
+	names := make(map[string]bool)
+	for _, site := range m.Sites {
+		if site.Name == "" || names[site.Name] {
+			return nil, fmt.Errorf("Invalid manifest[%s] : Sites require unique names", path)
+		}
+
+		if len(site.Seeds) == 0 {
+			return nil, fmt.Errorf("Invalid manifest[%s] : Site[%s] has no seeds", path, site.Name)
+		}
+
+		names[site.Name] = true
+	}
+
+	return &m, nil
+}
+
+// Seeds returns the seeds of every site within the manifest.
+func (m *Manifest) Seeds() []Seed {
+	var seeds []Seed
+
+	for _, site := range m.Sites {
+		for _, u := range site.Seeds {
+			seeds = append(seeds, Seed{URL: u, Scope: site.Scope, Site: site.Name})
+		}
+	}
+
+	return seeds
+}
@@ -0,0 +1,177 @@
+package spidy_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// TestManifest tests crawling several named sites together.
+func TestManifest(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to crawl several sites together")
+	{
+		var externalChecks int64
+
+		external := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			atomic.AddInt64(&externalChecks, 1)
+			res.Header().Set("Content-Type", "image/png")
+		}))
+
+		defer external.Close()
+
+		site := func(dead string) *httptest.Server {
+			return httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				if req.URL.Path != "/" {
+					res.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				fmt.Fprintf(res, `<html><body><img src="%s/shared.png"><a href="%s">Dead</a></body></html>`, external.URL, dead)
+			}))
+		}
+
+		docs := site("/dead-doc")
+		defer docs.Close()
+
+		blog := site("/dead-post")
+		defer blog.Close()
+
+		file, err := ioutil.TempFile("", "spidy-manifest")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a manifest file: %q", tests.Failed, err)
+		}
+		defer os.Remove(file.Name())
+
+		fmt.Fprintf(file, `{"sites": [{"name": "docs", "seeds": [%q]}, {"name": "blog", "seeds": [%q]}]}`, docs.URL, blog.URL)
+		file.Close()
+
+		t.Logf("\tWhen crawling the sites of a manifest")
+		{
+			manifest, err := spidy.LoadManifest(file.Name())
+			if err != nil {
+				t.Fatalf("\t%s\tShould have loaded the manifest: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have loaded the manifest", tests.Success)
+
+			conf := spidy.Config{
+				Client:  &http.Client{Timeout: 30 * time.Second},
+				All:     true,
+				Workers: 30,
+				Depth:   -1,
+				Events:  events,
+				Seeds:   manifest.Seeds(),
+			}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have successfully crawled the sites: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have successfully crawled the sites", tests.Success)
+
+			if atomic.LoadInt64(&externalChecks) != 1 {
+				t.Fatalf("\t%s\tShould have checked the shared external link once: %d", tests.Failed, externalChecks)
+			}
+			t.Logf("\t%s\tShould have checked the shared external link once", tests.Success)
+
+			expected := map[string]string{
+				docs.URL + "/dead-doc":  "docs",
+				blog.URL + "/dead-post": "blog",
+			}
+
+			if len(report.Links) != len(expected) {
+				t.Fatalf("\t%s\tShould have found a dead link per site: %+v", tests.Failed, report.Links)
+			}
+
+			for _, link := range report.Links {
+				if expected[link.Link] != link.Site {
+					t.Fatalf("\t%s\tShould have attributed dead link[%s] to site[%s]: %+v", tests.Failed, link.Link, expected[link.Link], link)
+				}
+			}
+			t.Logf("\t%s\tShould have found a dead link per site", tests.Success)
+
+			if len(report.Sites) != 2 || report.Sites[0].Name != "docs" || report.Sites[0].Errors != 1 || report.Sites[1].Errors != 1 {
+				t.Fatalf("\t%s\tShould have summarized the findings per site: %+v", tests.Failed, report.Sites)
+			}
+			t.Logf("\t%s\tShould have summarized the findings per site", tests.Success)
+
+			var buf bytes.Buffer
+			if err := spidy.WriteReport(&buf, report, spidy.FormatJSON); err != nil {
+				t.Fatalf("\t%s\tShould have written the report as JSON: %q", tests.Failed, err)
+			}
+
+			var decoded spidy.Report
+			if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.Links) != 2 || decoded.Links[0].Error == nil {
+				t.Fatalf("\t%s\tShould have written a decodable JSON report: %v : %s", tests.Failed, err, buf.String())
+			}
+			t.Logf("\t%s\tShould have written a decodable JSON report", tests.Success)
+		}
+
+		t.Logf("\tWhen crawling sites which link to each other")
+		{
+			// The shop is only found through a link from the home site,
+			// and the dead link is on the shop's page.
+			shop := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				switch req.URL.Path {
+				case "/":
+					fmt.Fprintf(res, `<html><body>Shop</body></html>`)
+				case "/offers":
+					fmt.Fprintf(res, `<html><body><a href="/expired">Expired</a></body></html>`)
+				default:
+					res.WriteHeader(http.StatusNotFound)
+				}
+			}))
+
+			defer shop.Close()
+
+			home := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				fmt.Fprintf(res, `<html><body><a href="%s/offers">Offers</a></body></html>`, shop.URL)
+			}))
+
+			defer home.Close()
+
+			manifest := spidy.Manifest{Sites: []spidy.Site{
+				{Name: "home", Seeds: []string{home.URL}},
+				{Name: "shop", Seeds: []string{shop.URL}},
+			}}
+
+			conf := spidy.Config{
+				Client:  &http.Client{Timeout: 30 * time.Second},
+				Workers: 30,
+				Depth:   -1,
+				Events:  events,
+				Seeds:   manifest.Seeds(),
+			}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have successfully crawled the sites: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have successfully crawled the sites", tests.Success)
+
+			if len(report.Links) != 1 || report.Links[0].Link != shop.URL+"/expired" || report.Links[0].Site != "shop" {
+				t.Fatalf("\t%s\tShould have attributed the dead link to the site of its page: %+v", tests.Failed, report.Links)
+			}
+			t.Logf("\t%s\tShould have attributed the dead link to the site of its page", tests.Success)
+
+			if len(report.Sites) != 2 || report.Sites[0].Errors != 0 || report.Sites[1].Errors != 1 {
+				t.Fatalf("\t%s\tShould have summarized the dead link under its site: %+v", tests.Failed, report.Sites)
+			}
+			t.Logf("\t%s\tShould have summarized the dead link under its site", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package spidy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Rules of the SEO audit, findings of each rule are reported with the kind
+// "seo-" followed by the rule, e.g seo-missing-title.
+const (
+	SEOMissingTitle         = "missing-title"
+	SEODuplicateTitle       = "duplicate-title"
+	SEOLongTitle            = "long-title"
+	SEOMissingDescription   = "missing-description"
+	SEODuplicateDescription = "duplicate-description"
+	SEOMissingH1            = "missing-h1"
+	SEOMultipleH1           = "multiple-h1"
+	SEOCanonical            = "canonical"
+	SEONoindexLinked        = "noindex-linked"
+	SEOOrphan               = "orphan"
+)
+
+// defaultMaxTitle is the length in characters past which titles are too long.
+const defaultMaxTitle = 60
+
+// seoRules lists every rule of the SEO audit.
+var seoRules = []string{
+	SEOMissingTitle, SEODuplicateTitle, SEOLongTitle,
+	SEOMissingDescription, SEODuplicateDescription,
+	SEOMissingH1, SEOMultipleH1,
+	SEOCanonical, SEONoindexLinked, SEOOrphan,
+}
+
+// SEO configures the SEO audit of the pages of a crawl. Every rule is run
+// unless skipped.
+type SEO struct {
+	Skip     []string // Rules not to run.
+	MaxTitle int      // Length in characters past which titles are too long, defaults to 60.
+}
+
+//==============================================================================
+
+// seoInspector audits the pages of a crawl for common SEO issues. Rules which
+// span pages, such as duplicate titles, are reported once the crawl finishes.
+// It implements the SiteInspector interface.
+type seoInspector struct {
+	config   *Config
+	sitemaps *Config
+	seeds    []seed
+	rules    map[string]bool
+	maxTitle int
+
+	ml           sync.Mutex
+	pages        map[string]bool
+	aliases      map[string]string
+	titles       map[string][]string
+	descriptions map[string][]string
+	noindex      map[string]bool
+	inbound      map[string]*inboundLinks
+	canonicals   map[string]*canonicalStatus
+}
+
+// inboundLinks defines the hyperlinks to a page from other pages.
+type inboundLinks struct {
+	source string
+	count  int
+}
+
+// canonicalStatus defines the status a canonical URL answered with.
+type canonicalStatus struct {
+	once   sync.Once
+	status int
+	err    error
+}
+
+// newSEOInspector returns a new seoInspector for the config, reading the
+// sitemaps of the crawled hosts with the giving one, or nil if pages are not
+// audited.
+func newSEOInspector(c *Config, sitemaps *Config, seeds []seed) (*seoInspector, error) {
+	if c.SEO == nil {
+		return nil, nil
+	}
+
+	si := seoInspector{
+		config:       c,
+		sitemaps:     sitemaps,
+		seeds:        seeds,
+		rules:        make(map[string]bool),
+		maxTitle:     c.SEO.MaxTitle,
+		pages:        make(map[string]bool),
+		aliases:      make(map[string]string),
+		titles:       make(map[string][]string),
+		descriptions: make(map[string][]string),
+		noindex:      make(map[string]bool),
+		inbound:      make(map[string]*inboundLinks),
+		canonicals:   make(map[string]*canonicalStatus),
+	}
+
+	if si.maxTitle <= 0 {
+		si.maxTitle = defaultMaxTitle
+	}
+
+	for _, rule := range seoRules {
+		si.rules[rule] = true
+	}
+
+	for _, rule := range c.SEO.Skip {
+		if !si.rules[rule] {
+			return nil, fmt.Errorf("Invalid SEO rule[%s]", rule)
+		}
+
+		si.rules[rule] = false
+	}
+
+	return &si, nil
+}
+
+// Inspect implements the PageInspector interface, reporting the issues of
+// the page and recording what is needed for the rules which span pages.
+func (si *seoInspector) Inspect(context interface{}, pg *Page) []LinkReport {
+	var findings []LinkReport
+	page := pg.URL.String()
+	doc := pg.Document
+
+	// Links which redirect to a page already audited need not audit it again.
+	si.ml.Lock()
+	audited := si.pages[page]
+	si.pages[page] = true
+	si.aliases[pg.Link] = page
+	si.ml.Unlock()
+
+	if audited {
+		return nil
+	}
+
+	title := collapseSpace(doc.Find("title").First().Text())
+	switch {
+	case title == "":
+		findings = si.add(findings, SEOMissingTitle, LinkReport{Error: fmt.Errorf("Page has no title")})
+	case utf8.RuneCountInString(title) > si.maxTitle:
+		findings = si.add(findings, SEOLongTitle, LinkReport{Error: fmt.Errorf("Title is %d characters long, over %d", utf8.RuneCountInString(title), si.maxTitle)})
+	}
+
+	var description string
+	var robots []string
+
+	for _, meta := range doc.Find("meta[name]").Nodes {
+		name, _ := getAttr(meta.Attr, "name")
+		content, _ := getAttr(meta.Attr, "content")
+
+		switch strings.ToLower(name.Val) {
+		case "description":
+			description = collapseSpace(content.Val)
+		case "robots", "googlebot":
+			robots = append(robots, content.Val)
+		}
+	}
+
+	if description == "" {
+		findings = si.add(findings, SEOMissingDescription, LinkReport{Error: fmt.Errorf("Page has no meta description")})
+	}
+
+	switch h1 := doc.Find("h1").Length(); {
+	case h1 == 0:
+		findings = si.add(findings, SEOMissingH1, LinkReport{Error: fmt.Errorf("Page has no h1")})
+	case h1 > 1:
+		findings = si.add(findings, SEOMultipleH1, LinkReport{Error: fmt.Errorf("Page has %d h1 elements", h1)})
+	}
+
+	robots = append(robots, pg.Header["X-Robots-Tag"]...)
+	noindex := strings.Contains(strings.ToLower(strings.Join(robots, ",")), "noindex")
+
+	base := documentBase(doc, pg.URL)
+
+	if href, ok := doc.Find("link[rel~=canonical][href]").First().Attr("href"); ok && si.rules[SEOCanonical] {
+		if uri, err := parsePath(strings.TrimSpace(href), base); err == nil {
+			uri.Fragment = ""
+			canonical := uri.String()
+
+			if status, err := si.canonicalStatus(canonical); status != http.StatusOK {
+				if err == nil {
+					err = fmt.Errorf("Canonical link answered with status %d", status)
+				}
+
+				findings = si.add(findings, SEOCanonical, LinkReport{
+					Link:      canonical,
+					Source:    page,
+					Tag:       "link[href]",
+					Reference: "canonical",
+					Status:    status,
+					Error:     err,
+				})
+			}
+		}
+	}
+
+	// Only hyperlinks between pages count as internal navigation.
+	var hyperlinks []pageLink
+	for _, l := range extractLinks(doc) {
+		if l.Element == "a" || l.Element == "area" {
+			hyperlinks = append(hyperlinks, l)
+		}
+	}
+
+	hyperlinks = resolveLinks(hyperlinks, base)
+
+	si.ml.Lock()
+	defer si.ml.Unlock()
+
+	if title != "" {
+		si.titles[title] = append(si.titles[title], page)
+	}
+
+	if description != "" {
+		si.descriptions[description] = append(si.descriptions[description], page)
+	}
+
+	if noindex {
+		si.noindex[page] = true
+	}
+
+	for _, l := range hyperlinks {
+		if l.URL == page || l.URL == pg.Link {
+			continue
+		}
+
+		in, ok := si.inbound[l.URL]
+		if !ok {
+			in = &inboundLinks{source: page}
+			si.inbound[l.URL] = in
+		}
+
+		in.count++
+	}
+
+	return findings
+}
+
+// Finish implements the SiteInspector interface, reporting the issues which
+// span the pages of the crawl.
+func (si *seoInspector) Finish(context interface{}) []LinkReport {
+	si.ml.Lock()
+	defer si.ml.Unlock()
+
+	var findings []LinkReport
+
+	findings = si.duplicates(findings, SEODuplicateTitle, "Title", si.titles)
+	findings = si.duplicates(findings, SEODuplicateDescription, "Meta description", si.descriptions)
+
+	// Links may point at a page through a redirect.
+	inbound := make(map[string]*inboundLinks)
+	for target, in := range si.inbound {
+		inbound[target] = in
+		if page, ok := si.aliases[target]; ok {
+			inbound[page] = in
+		}
+	}
+
+	if si.rules[SEONoindexLinked] {
+		for _, page := range sortedKeys(si.noindex) {
+			if in, ok := inbound[page]; ok {
+				findings = si.add(findings, SEONoindexLinked, LinkReport{
+					Link:   page,
+					Source: in.source,
+					Error:  fmt.Errorf("Noindex page is linked to by %d internal links", in.count),
+				})
+			}
+		}
+	}
+
+	if si.rules[SEOOrphan] {
+		findings = si.orphans(findings, inbound)
+	}
+
+	return findings
+}
+
+// orphans adds a finding for every page which is crawled or listed within the
+// sitemaps of the crawled hosts but no other page links to.
+func (si *seoInspector) orphans(findings []LinkReport, inbound map[string]*inboundLinks) []LinkReport {
+	seeds := make(map[string]bool)
+	for _, s := range si.seeds {
+		seeds[s.url.String()] = true
+	}
+
+	candidates := make(map[string]bool)
+	listed := make(map[string]bool)
+	hosts := make(map[string]bool)
+
+	for page := range si.pages {
+		candidates[page] = true
+
+		if uri, err := url.Parse(page); err == nil {
+			hosts[uri.Scheme+"://"+uri.Host] = true
+		}
+	}
+
+	for _, host := range sortedKeys(hosts) {
+		for _, u := range readSitemap(host, si.sitemaps) {
+			uri, err := url.Parse(u.Loc)
+			if err != nil || !uri.IsAbs() || !inScope(si.seeds, uri) {
+				continue
+			}
+
+			uri.Fragment = ""
+			candidates[uri.String()] = true
+			listed[uri.String()] = true
+		}
+	}
+
+	for _, page := range sortedKeys(candidates) {
+		if seeds[page] || inbound[page] != nil {
+			continue
+		}
+
+		if final, ok := si.aliases[page]; ok && (seeds[final] || inbound[final] != nil) {
+			continue
+		}
+
+		var reference string
+		if listed[page] {
+			reference = "sitemap"
+		}
+
+		findings = si.add(findings, SEOOrphan, LinkReport{
+			Link:      page,
+			Reference: reference,
+			Error:     fmt.Errorf("No internal page links to this page"),
+		})
+	}
+
+	return findings
+}
+
+// duplicates adds a finding for every page which shares its value with other
+// pages.
+func (si *seoInspector) duplicates(findings []LinkReport, rule string, name string, values map[string][]string) []LinkReport {
+	if !si.rules[rule] {
+		return findings
+	}
+
+	var keys []string
+	for value, pages := range values {
+		if len(pages) > 1 {
+			keys = append(keys, value)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, value := range keys {
+		pages := values[value]
+		sort.Strings(pages)
+
+		for _, page := range pages {
+			findings = si.add(findings, rule, LinkReport{
+				Link:  page,
+				Error: fmt.Errorf("%s %q is shared with %d other pages", name, value, len(pages)-1),
+			})
+		}
+	}
+
+	return findings
+}
+
+// add appends the finding of the giving rule if the rule is run.
+func (si *seoInspector) add(findings []LinkReport, rule string, f LinkReport) []LinkReport {
+	if !si.rules[rule] {
+		return findings
+	}
+
+	f.Kind = "seo-" + rule
+	f.Severity = SeverityWarning
+
+	return append(findings, f)
+}
+
+// canonicalStatus returns the status the giving canonical URL answers with,
+// without following redirects as a canonical URL should not redirect.
+func (si *seoInspector) canonicalStatus(canonical string) (int, error) {
+	si.ml.Lock()
+	cs, ok := si.canonicals[canonical]
+	if !ok {
+		cs = &canonicalStatus{}
+		si.canonicals[canonical] = cs
+	}
+	si.ml.Unlock()
+
+	cs.once.Do(func() {
+		client := *si.config.Client
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+
+		res, err := client.Head(canonical)
+		if err != nil {
+			cs.status, cs.err = http.StatusInternalServerError, err
+			return
+		}
+
+		res.Body.Close()
+		cs.status = res.StatusCode
+	})
+
+	return cs.status, cs.err
+}
+
+// sortedKeys returns the keys of the giving set in order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
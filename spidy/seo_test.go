@@ -0,0 +1,147 @@
+package spidy_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// seoPages defines the pages of the site audited for SEO issues.
+var seoPages = map[string]string{
+	"/": `<html><head><title>Home</title><meta name="description" content="Welcome"></head><body>
+		<h1>Home</h1><a href="/a">A</a><a href="/b">B</a><a href="/hidden">Hidden</a><a href="/long">Long</a>
+	</body></html>`,
+	"/a": `<html><head><title>Same</title><meta name="description" content="Same description"><link rel="canonical" href="/moved"></head><body>
+		<h1>A</h1><h1>Again</h1>
+	</body></html>`,
+	"/b": `<html><head><title>Same</title><meta name="description" content="Same description"><link rel="canonical" href="/b"></head><body>
+		<h1>B</h1>
+	</body></html>`,
+	"/hidden": `<html><head><title>Hidden</title><meta name="description" content="Hidden"><meta name="robots" content="noindex, follow"></head><body>
+		<h1>Hidden</h1>
+	</body></html>`,
+	"/long": `<html><head><title>` + strings.Repeat("Long ", 20) + `</title></head><body>
+		<p>No heading</p>
+	</body></html>`,
+	"/orphan": `<html><head><title>Orphan</title><meta name="description" content="Orphan"></head><body>
+		<h1>Orphan</h1>
+	</body></html>`,
+}
+
+// TestSEO tests auditing the pages of a site for SEO issues.
+func TestSEO(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to audit the pages of a site for SEO issues")
+	{
+		var server *httptest.Server
+
+		server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/sitemap.xml" {
+				res.Header().Set("Content-Type", "application/xml")
+				fmt.Fprintf(res, `<?xml version="1.0" encoding="UTF-8"?>
+					<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+						<url><loc>%s/</loc></url><url><loc>%s/a</loc></url><url><loc>%s/orphan</loc></url>
+					</urlset>`, server.URL, server.URL, server.URL)
+				return
+			}
+
+			if req.URL.Path == "/moved" {
+				http.Redirect(res, req, "/b", http.StatusMovedPermanently)
+				return
+			}
+
+			page, ok := seoPages[req.URL.Path]
+			if !ok {
+				res.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			res.Header().Set("Content-Type", "text/html")
+			res.Write([]byte(page))
+		}))
+
+		defer server.Close()
+
+		audit := func(seo *spidy.SEO) []string {
+			conf := spidy.Config{
+				Client:  &http.Client{Timeout: 30 * time.Second},
+				URL:     server.URL,
+				Workers: 10,
+				Depth:   -1,
+				Events:  events,
+				SEO:     seo,
+			}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have crawled the site", tests.Success)
+
+			var found []string
+			for _, f := range report.Links {
+				found = append(found, f.Kind+" "+strings.TrimPrefix(f.Link, server.URL))
+
+				if f.Severity != spidy.SeverityWarning {
+					t.Errorf("\t%s\tShould have reported %s as a warning", tests.Failed, f.Kind)
+				}
+			}
+
+			sort.Strings(found)
+			return found
+		}
+
+		t.Logf("\tWhen auditing the site with every rule")
+		{
+			found := audit(&spidy.SEO{})
+
+			expected := []string{
+				"seo-canonical /moved",
+				"seo-duplicate-description /a",
+				"seo-duplicate-description /b",
+				"seo-duplicate-title /a",
+				"seo-duplicate-title /b",
+				"seo-long-title /long",
+				"seo-missing-description /long",
+				"seo-missing-h1 /long",
+				"seo-multiple-h1 /a",
+				"seo-noindex-linked /hidden",
+				"seo-orphan /orphan",
+			}
+
+			if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+				t.Errorf("\t%s\tShould have found the SEO issues:\n%s\n\tbut got:\n%s", tests.Failed, strings.Join(expected, "\n"), strings.Join(found, "\n"))
+			} else {
+				t.Logf("\t%s\tShould have found the SEO issues", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen auditing the site with rules skipped")
+		{
+			found := audit(&spidy.SEO{Skip: []string{spidy.SEODuplicateTitle, spidy.SEOOrphan}, MaxTitle: 200})
+
+			for _, f := range found {
+				if strings.HasPrefix(f, "seo-duplicate-title") || strings.HasPrefix(f, "seo-orphan") || strings.HasPrefix(f, "seo-long-title") {
+					t.Errorf("\t%s\tShould not have run the skipped rules but got %s", tests.Failed, f)
+				}
+			}
+
+			if len(found) != 7 {
+				t.Errorf("\t%s\tShould have run the other rules but got %v", tests.Failed, found)
+			} else {
+				t.Logf("\t%s\tShould have only run the rules which are not skipped", tests.Success)
+			}
+		}
+	}
+}
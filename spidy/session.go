@@ -0,0 +1,194 @@
+package spidy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// logoutPattern matches links which look like they end the current session,
+// these are skipped when crawling with a session so we don't log ourselves out.
+// Only whole path segments or query keys and values match, so links such as
+// /blog-outdoor-gear are still crawled.
+var logoutPattern = regexp.MustCompile(`(?i)(^|[/?&=_.-])(log|sign)[-_]?(out|off)([/?&=._-]|$)`)
+
+//==============================================================================
+
+// Login defines a form based login which is performed before the crawl begins,
+// the cookies it sets are stored in the cookie jar used for the crawl.
+type Login struct {
+	URL    string     // URL the login form is posted to.
+	Fields url.Values // Form fields to post, e.g username and password.
+	Expect string     // Text expected within the response body on success.
+	Cookie string     // Name of the cookie expected to be set on success.
+}
+
+// startSession prepares the cookie jar for the crawl, importing any cookies
+// from the configured cookie file and performing the form login if one was
+// given. It returns a non-nil error if any of these steps failed.
+func startSession(context interface{}, c *Config) error {
+	if c.Login == nil && c.Cookies == "" {
+		return nil
+	}
+
+	c.Events.Event(context, "startSession", "Started : Cookies[%s] : Login[%t]", c.Cookies, c.Login != nil)
+
+	if c.Client.Jar == nil {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			c.Events.ErrorEvent(context, "startSession", err, "Completed")
+			return err
+		}
+
+		c.Client.Jar = jar
+	}
+
+	if c.Cookies != "" {
+		if err := importCookies(c.Client.Jar, c.Cookies); err != nil {
+			c.Events.ErrorEvent(context, "startSession", err, "Completed")
+			return err
+		}
+	}
+
+	if c.Login != nil {
+		if err := login(c.Client, c.Login); err != nil {
+			c.Events.ErrorEvent(context, "startSession", err, "Completed")
+			return err
+		}
+	}
+
+	c.Events.Event(context, "startSession", "Completed")
+	return nil
+}
+
+// login posts the login form and validates the response against the success
+// checks of the giving Login.
+func login(client *http.Client, l *Login) error {
+	res, err := client.PostForm(l.URL, l.Fields)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Login Failed : Status[%d]", res.StatusCode)
+	}
+
+	if l.Expect != "" {
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+
+		if !strings.Contains(string(body), l.Expect) {
+			return errors.New("Login Failed : Expected text not found")
+		}
+	}
+
+	if l.Cookie != "" {
+		var found bool
+		for _, cookie := range client.Jar.Cookies(res.Request.URL) {
+			if cookie.Name == l.Cookie {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("Login Failed : Cookie[%s] not set", l.Cookie)
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================
+
+// importCookies loads the cookies within the Netscape cookies.txt file at the
+// giving path into the provided jar.
+func importCookies(jar http.CookieJar, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return readCookies(jar, file)
+}
+
+// readCookies parses the Netscape cookies.txt format, where each line holds
+// the tab separated domain, subdomain flag, path, secure flag, expiry, name
+// and value of a cookie.
+func readCookies(jar http.CookieJar, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+
+	var line int
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		// Curl marks HttpOnly cookies with a prefix on an otherwise commented
+		// out line.
+		httpOnly := strings.HasPrefix(text, "#HttpOnly_")
+		if httpOnly {
+			text = strings.TrimPrefix(text, "#HttpOnly_")
+		}
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return fmt.Errorf("Invalid cookie at line %d : Expected 7 fields got %d", line, len(fields))
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid cookie expiry at line %d : %s", line, err)
+		}
+
+		host := strings.TrimPrefix(fields[0], ".")
+		secure := strings.EqualFold(fields[3], "TRUE")
+
+		cookie := http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   secure,
+			HttpOnly: httpOnly,
+		}
+
+		// Only cookies which include subdomains carry a domain, the others are
+		// host-only cookies.
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = host
+		}
+
+		// An expiry of zero marks a session cookie.
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: cookie.Path}, []*http.Cookie{&cookie})
+	}
+
+	return scanner.Err()
+}
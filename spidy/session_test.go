@@ -0,0 +1,169 @@
+package spidy_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// sessionPage provides a page behind a login with logout links, and missing
+// links which only look like logout links.
+var sessionPage = []byte(`
+<!DOCTYPE html>
+<html>
+<body>
+		<a href="/services"></a>
+		<a href="/logout"></a>
+		<a href="/account/sign-out"></a>
+		<a href="/session?action=logoff"></a>
+		<a href="/missing"></a>
+		<a href="/blog-outdoor-gear"></a>
+		<a href="/catalog_offers"></a>
+</body>
+</html>`)
+
+// TestSession tests the crawling of pages which require a session cookie set
+// either through a form login or a cookies file.
+func TestSession(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to crawl pages behind a session")
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/login" {
+				req.ParseForm()
+				if req.FormValue("user") != "bob" {
+					res.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				http.SetCookie(res, &http.Cookie{Name: "session", Value: "bob", Path: "/"})
+				res.Write([]byte("Welcome bob"))
+				return
+			}
+
+			// Logging out removes the session, which would fail every page after.
+			if req.URL.Path == "/logout" || req.URL.Path == "/account/sign-out" || req.URL.Query().Get("action") == "logoff" {
+				http.SetCookie(res, &http.Cookie{Name: "session", Value: "", Path: "/", MaxAge: -1})
+				return
+			}
+
+			if cookie, err := req.Cookie("session"); err != nil || cookie.Value != "bob" {
+				res.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			switch req.URL.Path {
+			case "/missing", "/blog-outdoor-gear", "/catalog_offers":
+				res.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			res.Write(sessionPage)
+		}))
+
+		defer server.Close()
+
+		t.Logf("\tWhen logging in through a form")
+		{
+			conf := spidy.Config{
+				Client:  &http.Client{Timeout: 30 * time.Second},
+				URL:     server.URL,
+				Workers: 30,
+				Depth:   -1,
+				Events:  events,
+				Login: &spidy.Login{
+					URL:    server.URL + "/login",
+					Fields: url.Values{"user": {"bob"}},
+					Expect: "Welcome",
+					Cookie: "session",
+				},
+			}
+
+			testSessionCrawl(conf, t)
+		}
+
+		t.Logf("\tWhen loading cookies from a cookies file")
+		{
+			host, _ := url.Parse(server.URL)
+
+			file, err := ioutil.TempFile("", "spidy-cookies")
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to create a cookies file: %q", tests.Failed, err)
+			}
+			defer os.Remove(file.Name())
+
+			fmt.Fprintf(file, "# Netscape HTTP Cookie File\n%s\tFALSE\t/\tFALSE\t0\tsession\tbob\n", host.Host)
+			file.Close()
+
+			conf := spidy.Config{
+				Client:  &http.Client{Timeout: 30 * time.Second},
+				URL:     server.URL,
+				Workers: 30,
+				Depth:   -1,
+				Events:  events,
+				Cookies: file.Name(),
+			}
+
+			testSessionCrawl(conf, t)
+		}
+
+		t.Logf("\tWhen logging in with invalid credentials")
+		{
+			conf := spidy.Config{
+				Client:  &http.Client{Timeout: 30 * time.Second},
+				URL:     server.URL,
+				Workers: 30,
+				Depth:   -1,
+				Events:  events,
+				Login: &spidy.Login{
+					URL:    server.URL + "/login",
+					Fields: url.Values{"user": {"alice"}},
+				},
+			}
+
+			if _, err := spidy.Run(context, &conf); err == nil {
+				t.Fatalf("\t%s\tShould have failed to login", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have failed to login", tests.Success)
+		}
+	}
+}
+
+// testSessionCrawl crawls the session page and asserts only the missing links
+// were reported, which requires the session to be kept throughout the crawl
+// and the links which only look like logout links to be checked.
+func testSessionCrawl(c spidy.Config, t *testing.T) {
+	badlinks, err := spidy.Run(context, &c)
+	if err != nil {
+		t.Fatalf("\t%s\tShould have successfully retrieved page[%s]: %q", tests.Failed, c.URL, err)
+	}
+	t.Logf("\t%s\tShould have successfully retrieved page[%s]", tests.Success, c.URL)
+
+	var missing []string
+	for _, link := range badlinks {
+		if link.Status == http.StatusNotFound {
+			missing = append(missing, strings.TrimPrefix(link.Link, c.URL))
+		}
+	}
+
+	sort.Strings(missing)
+
+	if len(badlinks) != 3 || strings.Join(missing, ",") != "/blog-outdoor-gear,/catalog_offers,/missing" {
+		t.Fatalf("\t%s\tShould have found only the missing links in page[%s]: %+v", tests.Failed, c.URL, badlinks)
+	}
+	t.Logf("\t%s\tShould have found only the missing links in page[%s]", tests.Success, c.URL)
+}
@@ -0,0 +1,80 @@
+package spidy
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+)
+
+// maxSitemaps limits how many sitemaps of a sitemap index are read.
+const maxSitemaps = 50
+
+// sitemapURL defines a URL listed within a sitemap.
+type sitemapURL struct {
+	Loc      string  `xml:"loc"`
+	Priority float64 `xml:"priority"`
+}
+
+// sitemapDoc defines the document of a sitemap or sitemap index.
+type sitemapDoc struct {
+	URLs     []sitemapURL `xml:"url"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// readSitemap returns the URLs listed within the sitemap of the giving host
+// (e.g https://example.com), following the sitemaps of a sitemap index. A
+// host without a sitemap has no URLs.
+func readSitemap(host string, c *Config) []sitemapURL {
+	var urls []sitemapURL
+
+	pending := []string{strings.TrimSuffix(host, "/") + "/sitemap.xml"}
+	seen := make(map[string]bool)
+
+	for len(pending) > 0 && len(seen) < maxSitemaps {
+		loc := pending[0]
+		pending = pending[1:]
+
+		if seen[loc] {
+			continue
+		}
+
+		seen[loc] = true
+
+		doc, ok := fetchSitemap(loc, c)
+		if !ok {
+			continue
+		}
+
+		for _, u := range doc.URLs {
+			u.Loc = strings.TrimSpace(u.Loc)
+			urls = append(urls, u)
+		}
+
+		for _, s := range doc.Sitemaps {
+			pending = append(pending, strings.TrimSpace(s.Loc))
+		}
+	}
+
+	return urls
+}
+
+// fetchSitemap fetches and decodes the sitemap at the giving URL.
+func fetchSitemap(loc string, c *Config) (*sitemapDoc, bool) {
+	res, err := c.Client.Get(loc)
+	if err != nil {
+		return nil, false
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	var doc sitemapDoc
+	if err := xml.NewDecoder(res.Body).Decode(&doc); err != nil {
+		return nil, false
+	}
+
+	return &doc, true
+}
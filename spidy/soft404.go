@@ -0,0 +1,285 @@
+package spidy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Soft404 configures the detection of soft 404s, pages which answer with a
+// 2xx status but read as not found. Each heuristic adds to the confidence of
+// a page being a soft 404, which is reported if it reaches the Threshold.
+type Soft404 struct {
+
+	// Probe requests a random nonexistent URL of each host and compares the
+	// pages of the host against its response, if it answers with a 2xx.
+	Probe bool
+
+	// Titles and Bodies are regular expressions matched against the title
+	// and text of pages, they default to DefaultSoft404Titles and
+	// DefaultSoft404Bodies.
+	Titles []string
+	Bodies []string
+
+	// Threshold is the confidence, from 0 to 1, at which pages are reported,
+	// it defaults to 0.5.
+	Threshold float64
+}
+
+// Default patterns of the titles and text of not found pages.
+var (
+	DefaultSoft404Titles = []string{
+		`(?i)\b(page|file|article|content)?\s*not\s+found\b`,
+		`(?i)\b404\b`,
+		`(?i)\b(does\s*n[o']t|no\s+longer)\s+exists?\b`,
+	}
+
+	DefaultSoft404Bodies = []string{
+		`(?i)\b(page|file|article|content)\s+(you\s+(are|were|'re)\s+looking\s+for|you\s+requested)?\s*(could\s*n[o']t|can\s*n[o']t|can't|was\s+not|has\s+not)\s+(be\s+|been\s+)?found\b`,
+		`(?i)\b404\s*(error|-|:)?\s*(page\s+)?not\s+found\b`,
+	}
+)
+
+// Confidences of each of the soft 404 heuristics, the similarity to the
+// not-found page of the host is used as is.
+// Pages which share the boilerplate of a site are somewhat similar to its
+// not-found page, so only pages past minSimilarity are considered.
+const (
+	titleConfidence = 0.8
+	bodyConfidence  = 0.6
+	minSimilarity   = 0.8
+)
+
+//==============================================================================
+
+// soft404Probe detects soft 404s, caching the not-found page of each host.
+type soft404Probe struct {
+	config    *Config
+	titles    []*regexp.Regexp
+	bodies    []*regexp.Regexp
+	threshold float64
+	ml        sync.Mutex
+	hosts     map[string]*notFoundPage
+}
+
+// notFoundPage defines the page a host answers nonexistent URLs with, its
+// shingles are nil if the host answers them with an error status.
+type notFoundPage struct {
+	once     sync.Once
+	final    string
+	shingles map[uint64]bool
+}
+
+// newSoft404Probe returns a new soft404Probe for the config, or nil if soft
+// 404s are not detected.
+func newSoft404Probe(c *Config) (*soft404Probe, error) {
+	if c.Soft404 == nil {
+		return nil, nil
+	}
+
+	sp := soft404Probe{
+		config:    c,
+		threshold: c.Soft404.Threshold,
+		hosts:     make(map[string]*notFoundPage),
+	}
+
+	if sp.threshold <= 0 {
+		sp.threshold = 0.5
+	}
+
+	titles := c.Soft404.Titles
+	if titles == nil {
+		titles = DefaultSoft404Titles
+	}
+
+	bodies := c.Soft404.Bodies
+	if bodies == nil {
+		bodies = DefaultSoft404Bodies
+	}
+
+	var err error
+	if sp.titles, err = compilePatterns(titles); err != nil {
+		return nil, err
+	}
+
+	if sp.bodies, err = compilePatterns(bodies); err != nil {
+		return nil, err
+	}
+
+	return &sp, nil
+}
+
+// Inspect implements the PageInspector interface, returning a soft 404
+// finding for the page if it reads as not found with enough confidence.
+func (sp *soft404Probe) Inspect(context interface{}, pg *Page) []LinkReport {
+	title := collapseSpace(pg.Document.Find("title").First().Text())
+	text := collapseSpace(pg.Document.Find("body").Text())
+
+	// Combine the heuristics as independent evidence.
+	doubt := 1.0
+	var reasons []string
+
+	// Hosts may redirect nonexistent URLs to a page such as their home page,
+	// which is only a soft 404 when reached through a redirect.
+	nf := sp.notFound(pg.URL)
+	if nf.shingles != nil && (pg.Link != nf.final || pg.URL.String() != nf.final) {
+		if similarity := jaccard(shingles(text), nf.shingles); similarity >= minSimilarity {
+			doubt *= 1 - similarity
+			reasons = append(reasons, fmt.Sprintf("%.0f%% similar to the not-found page of the host", similarity*100))
+		}
+	}
+
+	if matchAny(sp.titles, title) {
+		doubt *= 1 - titleConfidence
+		reasons = append(reasons, "title reads as not found")
+	}
+
+	if matchAny(sp.bodies, text) {
+		doubt *= 1 - bodyConfidence
+		reasons = append(reasons, "text reads as not found")
+	}
+
+	confidence := 1 - doubt
+	if confidence < sp.threshold {
+		return nil
+	}
+
+	return []LinkReport{{
+		Error:      fmt.Errorf("Page looks like a soft 404 : %s", strings.Join(reasons, ", ")),
+		Kind:       KindSoft404,
+		Confidence: confidence,
+	}}
+}
+
+// notFound returns the not-found page of the host of the giving URL, probing
+// the host for it once.
+func (sp *soft404Probe) notFound(page *url.URL) *notFoundPage {
+	if !sp.config.Soft404.Probe {
+		return &notFoundPage{}
+	}
+
+	host := page.Scheme + "://" + page.Host
+
+	sp.ml.Lock()
+	nf, ok := sp.hosts[host]
+	if !ok {
+		nf = &notFoundPage{}
+		sp.hosts[host] = nf
+	}
+	sp.ml.Unlock()
+
+	nf.once.Do(func() {
+		sp.probe(host, nf)
+	})
+
+	return nf
+}
+
+// probe requests a random nonexistent URL of the giving host, recording the
+// page it answers with if it answers with a 2xx.
+func (sp *soft404Probe) probe(host string, nf *notFoundPage) {
+	token := make([]byte, 12)
+	if _, err := rand.Read(token); err != nil {
+		return
+	}
+
+	res, err := sp.config.Client.Get(host + "/" + hex.EncodeToString(token))
+	if err != nil {
+		return
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return
+	}
+
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return
+	}
+
+	nf.final = res.Request.URL.String()
+	nf.shingles = shingles(collapseSpace(doc.Find("body").Text()))
+}
+
+//==============================================================================
+
+// compilePatterns compiles the giving regular expressions.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid pattern[%s] : %s", pattern, err)
+		}
+
+		res = append(res, re)
+	}
+
+	return res, nil
+}
+
+// matchAny reports whether any of the giving regular expressions match text.
+func matchAny(res []*regexp.Regexp, text string) bool {
+	for _, re := range res {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collapseSpace returns the giving text with runs of whitespace collapsed
+// into single spaces.
+func collapseSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// shingleSize is the number of words within each shingle.
+const shingleSize = 3
+
+// shingles returns the hashes of the runs of words of the giving text.
+func shingles(text string) map[uint64]bool {
+	words := strings.Fields(strings.ToLower(text))
+	set := make(map[uint64]bool)
+
+	// Texts shorter than a shingle are a single shingle.
+	size := shingleSize
+	if len(words) < size {
+		size = len(words)
+	}
+
+	for i := 0; i+size <= len(words) && size > 0; i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+size], " ")))
+		set[h.Sum64()] = true
+	}
+
+	return set
+}
+
+// jaccard returns the similarity, from 0 to 1, of two sets of shingles.
+func jaccard(a map[uint64]bool, b map[uint64]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	var shared int
+	for s := range a {
+		if b[s] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,112 @@
+package spidy_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// TestSoft404 tests detecting pages which answer 200 but read as not found.
+func TestSoft404(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to detect soft 404s")
+	{
+		nav := `<nav><a href="/">Home</a> <a href="/real">Real</a></nav>`
+
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.Header().Set("Content-Type", "text/html")
+
+			switch req.URL.Path {
+			case "/":
+				fmt.Fprintf(res, `<html><head><title>Home</title></head><body>%s<a href="/gone">Gone</a><a href="/titled">Titled</a></body></html>`, nav)
+			case "/real":
+				fmt.Fprintf(res, `<html><head><title>Real</title></head><body>%s<p>Spidy crawls every page of a site looking for links which are broken.</p></body></html>`, nav)
+			case "/titled":
+				fmt.Fprintf(res, `<html><head><title>Page Not Found</title></head><body>%s<p>The cat ate this one while chasing a mouse across the keyboard.</p></body></html>`, nav)
+			default:
+				fmt.Fprintf(res, `<html><head><title>Example</title></head><body>%s<p>Sorry, nothing lives at this address. Try the search box or head back home.</p></body></html>`, nav)
+			}
+		}))
+
+		defer server.Close()
+
+		t.Logf("\tWhen crawling a site which answers missing pages with a 200")
+		{
+			conf := spidy.Config{
+				Client:  &http.Client{Timeout: 30 * time.Second},
+				URL:     server.URL,
+				Workers: 10,
+				Depth:   -1,
+				Events:  events,
+				Soft404: &spidy.Soft404{Probe: true},
+			}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have crawled the site", tests.Success)
+
+			confidences := make(map[string]float64)
+			for _, f := range report.Links {
+				if f.Kind != spidy.KindSoft404 {
+					t.Errorf("\t%s\tShould only have found soft 404s but got %s for %s", tests.Failed, f.Kind, f.Link)
+					continue
+				}
+
+				confidences[f.Link] = f.Confidence
+			}
+
+			if c := confidences[server.URL+"/gone"]; c < 0.99 {
+				t.Errorf("\t%s\tShould have matched the page against the not-found page of the host but got %.2f", tests.Failed, c)
+			} else {
+				t.Logf("\t%s\tShould have matched the page against the not-found page of the host", tests.Success)
+			}
+
+			if c := confidences[server.URL+"/titled"]; c < 0.79 || c > 0.81 {
+				t.Errorf("\t%s\tShould have matched the title of the page against the patterns but got %.2f", tests.Failed, c)
+			} else {
+				t.Logf("\t%s\tShould have matched the title of the page against the patterns", tests.Success)
+			}
+
+			if len(confidences) != 2 {
+				t.Errorf("\t%s\tShould have only reported the soft 404s but got %v", tests.Failed, confidences)
+			} else {
+				t.Logf("\t%s\tShould have only reported the soft 404s", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen crawling the site with a threshold above the title heuristic")
+		{
+			conf := spidy.Config{
+				Client:  &http.Client{Timeout: 30 * time.Second},
+				URL:     server.URL,
+				Workers: 10,
+				Depth:   -1,
+				Events:  events,
+				Soft404: &spidy.Soft404{Threshold: 0.9},
+			}
+
+			report, err := spidy.Crawl(context, &conf)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have crawled the site: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have crawled the site", tests.Success)
+
+			if len(report.Links) != 0 {
+				t.Errorf("\t%s\tShould not have reported pages below the threshold but got %d", tests.Failed, len(report.Links))
+			} else {
+				t.Logf("\t%s\tShould not have reported pages below the threshold", tests.Success)
+			}
+		}
+	}
+}
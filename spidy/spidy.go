@@ -3,11 +3,11 @@ package spidy
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
-	"sync"
-	"sync/atomic"
+	"time"
 
 	"golang.org/x/net/html"
 
@@ -24,11 +24,62 @@ type Events interface {
 
 //==============================================================================
 
+// Kinds of findings reported by spidy.
+const (
+	KindDeadLink     = "dead-link"
+	KindCertificate  = "certificate"
+	KindCertExpiry   = "certificate-expiry"
+	KindMixedContent = "mixed-content"
+	KindInsecureLink = "insecure-link"
+
+	KindSoft404            = "soft-404"
+	KindIntegrity          = "resource-integrity"
+	KindInspection         = "inspection"
+	KindExpiredSuppression = "expired-suppression"
+	KindDuplicate          = "duplicate-content"
+	KindNearDuplicate      = "near-duplicate-content"
+	KindBudget             = "budget-exhausted"
+	KindTruncated          = "truncated-body"
+	KindInvalidLink        = "invalid-link"
+)
+
+// Severity defines how serious a finding is.
+type Severity string
+
+// Severities of findings, only errors are considered failures of the crawl.
+// Findings of known or accepted failures are suppressed.
+const (
+	SeverityError      Severity = "error"
+	SeverityWarning    Severity = "warning"
+	SeveritySuppressed Severity = "suppressed"
+)
+
 // LinkReport defines a struct to entail failed links with their status and errors.
 type LinkReport struct {
-	Link   string
-	Status int
-	Error  error
+	Link      string
+	Source    string
+	Site      string
+	Tag       string
+	Reference string
+	Status    int
+	Error     error
+	Kind      string
+	Severity  Severity
+
+	// Confidence is how likely, from 0 to 1, a heuristic finding such as a
+	// soft 404 is right, it is 0 for findings which are certain.
+	Confidence float64
+}
+
+// newLinkReport returns a LinkReport for a link found within the source page
+// which failed evaluation, classifying the finding by the error it failed with.
+func newLinkReport(link string, source string, status int, err error) LinkReport {
+	kind := KindDeadLink
+	if err != nil && isCertError(err) {
+		kind = KindCertificate
+	}
+
+	return LinkReport{Link: link, Source: source, Status: status, Error: err, Kind: kind, Severity: SeverityError}
 }
 
 // Config defines the configuration through which our crawler defines its running
@@ -36,46 +87,289 @@ type LinkReport struct {
 type Config struct {
 	Client  *http.Client
 	URL     string
-	All     bool
 	Workers int
-	Depth   int
 	Events  Events
+
+	// All checks the links beyond the scope of the crawl with a single request
+	// each, without parsing or crawling them. Each link is checked once per
+	// crawl on a pool of ExternalWorkers of its own, defaulting to Workers, so
+	// slow external hosts don't hold up the pages being crawled.
+	All             bool
+	ExternalWorkers int
+
+	// Depth is the maximum number of links from the seeds of the pages
+	// crawled, the links of pages at that depth are checked but not crawled.
+	// A depth of zero or below is unlimited.
+	Depth int
+
+	// Login and Cookies optionally establish a session before crawling, the
+	// session cookies are kept within the Client's cookie jar.
+	Login   *Login
+	Cookies string
+
+	// CAFile, InsecureHosts and CertExpiry configure the auditing of the
+	// certificates of HTTPS hosts. CAFile is a PEM bundle of CAs trusted in
+	// addition to the system's, InsecureHosts are host patterns (e.g
+	// *.internal) whose certificates are not required to verify and
+	// CertExpiry is the number of days before expiry to warn at. Only Clients
+	// using a *http.Transport are audited, others are used as they are.
+	CAFile        string
+	InsecureHosts []string
+	CertExpiry    int
+
+	// Scope decides which links are internal to the crawl and so are
+	// crawled, external links are only checked if All is set.
+	Scope Scope
+
+	// Seeds are crawled in addition to URL, each within its own scope and
+	// sharing the checks of the links between them.
+	Seeds []Seed
+
+	// Dir optionally serves the pages under the Base URL from the files of a
+	// local directory, such as the output of a static site generator, in
+	// place of a web server. Base defaults to URL, links outside of it are
+	// still checked over HTTP.
+	Dir  string
+	Base string
+
+	// Suppressions demote the findings of known or accepted failures until
+	// they expire.
+	Suppressions []Suppression
+
+	// Integrity validates the content of the scripts, stylesheets and images
+	// pages load, fetching each of them in full.
+	Integrity bool
+
+	// Soft404 optionally detects pages which answer with a 2xx status but
+	// read as not found.
+	Soft404 *Soft404
+
+	// Inspectors run custom checks on every HTML page of the crawl, adding
+	// their findings to the report.
+	Inspectors []PageInspector
+
+	// SEO optionally audits the pages of the crawl for common SEO issues.
+	SEO *SEO
+
+	// A11y optionally lints the pages of the crawl for common accessibility
+	// issues.
+	A11y *A11y
+
+	// Duplicates optionally clusters the pages of the crawl published with
+	// the same or nearly the same content under several URLs.
+	Duplicates *Duplicates
+
+	// Graph records the internal link graph of the crawl and its analytics
+	// within the report.
+	Graph bool
+
+	// BodyLimits are the maximum sizes in bytes of the response bodies read
+	// per media type, with "*" for any other type, defaulting to
+	// DefaultBodyLimits. Bodies past their limit are cut short and reported,
+	// a limit of zero or below is unlimited.
+	BodyLimits map[string]int64
+
+	// Frontier holds the pages pending within the crawl and Visited the links
+	// already checked, both default to being held in memory. Crawls of huge
+	// sites can hold them on disk in bounded memory, see NewDiskFrontier and
+	// NewDiskVisited.
+	Frontier Frontier
+	Visited  VisitedSet
+
+	// Priority scores the pages pending within the crawl, which are crawled
+	// highest first, defaulting to DepthPriority for a breadth first crawl so
+	// a crawl cut short by its Budget covers the shallowest pages. Priorities
+	// only apply to frontiers held in memory, a DiskFrontier crawls pages in
+	// the order they were found.
+	Priority PriorityFunc
+
+	// PrioritizeSitemap adds the SitemapPriority of pages to Priority, with
+	// sitemaps read through the client of the crawl so those behind a login
+	// or a custom CA are read as its pages are.
+	PrioritizeSitemap bool
+
+	// Budget sets hard limits on the pages, requests, bytes and time the
+	// crawl may spend, which stops with a partial report once any is reached.
+	Budget Budget
 }
 
 // Run evaluates the given urlPath returning possible lists of deadlinks found
 // within the page of the given link else returns a non-nil error if it failed.
 func Run(context interface{}, c *Config) ([]LinkReport, error) {
-	c.Events.Event(context, "Run", "Started : URL[%s] : Include Externals[%t] : Workers[%d] : HTTPTimeout[%s]", c.URL, c.All, c.Workers, c.Client.Timeout)
+	report, err := Crawl(context, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return report.Links, nil
+}
+
+// Crawl evaluates the given urlPath and seeds returning the report of the
+// findings within their pages and the certificates of the HTTPS hosts
+// contacted, else returns a non-nil error if it failed.
+func Crawl(context interface{}, c *Config) (*Report, error) {
+	c.Events.Event(context, "Crawl", "Started : URL[%s] : Seeds[%d] : Include Externals[%t] : Workers[%d] : HTTPTimeout[%s]", c.URL, len(c.Seeds), c.All, c.Workers, c.Client.Timeout)
+
+	seeds, err := seedsOf(c)
+	if err != nil {
+		c.Events.ErrorEvent(context, "Crawl", err, "Completed")
+		return nil, err
+	}
+
+	conf, audit, err := prepare(context, c)
+	if err != nil {
+		c.Events.ErrorEvent(context, "Crawl", err, "Completed")
+		return nil, err
+	}
+
+	// Sitemaps are read once the crawl finishes, when its budget may be
+	// spent, so they are read with a client the budget does not limit.
+	sitemaps := *conf
+
+	budget := newCrawlBudget(c.Budget)
+	conf.Client = budget.client(conf.Client)
+
+	if conf.PrioritizeSitemap {
+		priority := sitemapPriority(conf)
+		if conf.Priority != nil {
+			priority = CombinePriority(conf.Priority, priority)
+		}
+
+		conf.Priority = priority
+	}
+
+	inspectors := c.Inspectors
 
-	path, err := url.Parse(c.URL)
+	soft, err := newSoft404Probe(conf)
 	if err != nil {
-		c.Events.ErrorEvent(context, "Run", err, "Completed")
+		c.Events.ErrorEvent(context, "Crawl", err, "Completed")
 		return nil, err
 	}
 
-	// deadLinks provides a lists to contain all failed url links that are
-	// considered deadlinks.
-	var deadLinks []LinkReport
+	if soft != nil {
+		inspectors = append(inspectors[:len(inspectors):len(inspectors)], soft)
+	}
+
+	seo, err := newSEOInspector(conf, &sitemaps, seeds)
+	if err != nil {
+		c.Events.ErrorEvent(context, "Crawl", err, "Completed")
+		return nil, err
+	}
+
+	if seo != nil {
+		inspectors = append(inspectors[:len(inspectors):len(inspectors)], seo)
+	}
+
+	a11y, err := newA11yInspector(conf)
+	if err != nil {
+		c.Events.ErrorEvent(context, "Crawl", err, "Completed")
+		return nil, err
+	}
+
+	if a11y != nil {
+		inspectors = append(inspectors[:len(inspectors):len(inspectors)], a11y)
+	}
+
+	dups := newDuplicateInspector(conf)
+	if dups != nil {
+		inspectors = append(inspectors[:len(inspectors):len(inspectors)], dups)
+	}
+
+	graph := newLinkGraph(conf)
+
+	report := Report{Started: time.Now().UTC()}
 
 	dead := make(chan LinkReport)
 
-	go collectFrom(c, path, dead)
+	go collectFrom(conf, seeds, inspectors, graph, budget, dead)
 
 	for link := range dead {
-		deadLinks = append(deadLinks, link)
+		report.Links = append(report.Links, link)
+	}
+
+	report.Links = append(report.Links, finishSite(context, inspectors, seeds)...)
+
+	if dups != nil {
+		report.Duplicates = dups.found()
 	}
 
-	c.Events.Event(context, "Run", "Completed : Total Dead Links[%d]", len(deadLinks))
-	return deadLinks, nil
+	// A crawl which ran out of budget is partial, which is reported so it
+	// is not mistaken for a complete one.
+	report.Budget = budget.report()
+	if report.Budget.Exhausted != "" {
+		report.Links = append(report.Links, LinkReport{
+			Link:     seeds[0].url.String(),
+			Site:     seeds[0].site,
+			Error:    fmt.Errorf("Crawl stopped at its %s budget with %d links unexplored", report.Budget.Exhausted, report.Budget.Unexplored),
+			Kind:     KindBudget,
+			Severity: SeverityWarning,
+		})
+	}
+
+	certs, warnings := audit.report(c.CertExpiry)
+	report.Certs = certs
+	report.Links = append(report.Links, warnings...)
+	report.Finished = time.Now().UTC()
+
+	report.suppress(c.Suppressions, report.Finished)
+	report.summarize(seeds)
+
+	if graph != nil {
+		report.Graph = graph.build(&sitemaps, seeds, report.Links)
+	}
+
+	c.Events.Event(context, "Crawl", "Completed : Total Findings[%d] : Total Certificates[%d]", len(report.Links), len(report.Certs))
+	return &report, nil
 }
 
-//==============================================================================
+// prepare returns the copy of the config a crawl works on, with a client
+// auditing TLS certificates through the returned audit and holding the
+// session, so neither leaks into the caller's client.
+func prepare(context interface{}, c *Config) (*Config, *tlsAudit, error) {
+	audit, err := newTLSAudit(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conf := *c
+	if conf.Client, err = audit.client(c.Client); err != nil {
+		return nil, nil, err
+	}
 
-var depths int64
+	if c.Dir != "" {
+		base := c.Base
+		if base == "" {
+			base = c.URL
+		}
+
+		transport, err := newDirTransport(c.Dir, base, conf.Client.Transport)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		client := *conf.Client
+		client.Transport = transport
+		conf.Client = &client
+	}
+
+	if err := startSession(context, &conf); err != nil {
+		return nil, nil, err
+	}
 
-// collectFrom uses a recursive function to map out the needed lists of links to.
-// It returns a channel through which the acceptable links can be crawled from.
-func collectFrom(c *Config, path *url.URL, dead chan LinkReport) {
+	return &conf, audit, nil
+}
+
+//==============================================================================
+
+// collectFrom uses a recursive function to map out the needed lists of links to
+// from each of the seeds. The pages found are pushed to the frontier of the
+// crawl, which is drained by the workers of its pool until no page is pending.
+// Crawls with a budget or a depth run in rounds of at most one page per
+// worker, each waiting on all of its pages so the pages they find are pending
+// before the next round pops the best of them, which crawls the same pages of
+// a site every run. Other crawls, which crawl every page whatever the order,
+// pop the best page pending as each worker frees up.
+func collectFrom(c *Config, seeds []seed, inspectors []PageInspector, graph *linkGraph, budget *crawlBudget, dead chan LinkReport) {
 	poolCfg := pool.Config{
 		OptEvent:    pool.OptEvent{Event: c.Events.Event},
 		MinRoutines: func() int { return 10 },
@@ -87,97 +381,221 @@ func collectFrom(c *Config, path *url.URL, dead chan LinkReport) {
 	// create a new worker pool.
 	pl, err := pool.New("spidy", "collectFrom", poolCfg)
 	if err != nil {
-		fmt.Printf("Spidy failed to create work pool: %s\n", err.Error())
+		c.Events.ErrorEvent("spidy", "collectFrom", err, "Failed to create work pool")
 		return
 	}
 
 	defer pl.Shutdown("spidy")
 
-	// Evalue the giving path and check if its a crawlable endpoint and
-	// if the status meets our criteria.
-	status, crawleable, err := evaluatePath(path.String(), c)
-	// fmt.Println("First::Evaluate: ", path, " Status: ", status)
-	if err != nil {
-		dead <- LinkReport{Link: path.String(), Status: status, Error: err}
-		return
+	cs := crawlState{
+		config:     c,
+		seeds:      seeds,
+		dead:       dead,
+		frontier:   c.Frontier,
+		visited:    c.Visited,
+		secure:     newSecureProbe(c),
+		inspectors: inspectors,
+		graph:      graph,
+		budget:     budget,
+		maxdepth:   c.Depth,
 	}
 
-	if !crawleable {
-		return
+	if c.All {
+		workers := c.ExternalWorkers
+		if workers <= 0 {
+			workers = c.Workers
+		}
+
+		ec, err := newExternalChecker(&cs, workers)
+		if err != nil {
+			c.Events.ErrorEvent("spidy", "collectFrom", err, "Failed to create work pool")
+			return
+		}
+
+		// The crawl is only done once every external link is checked.
+		defer ec.close()
+		cs.externals = ec
 	}
 
-	var wait sync.WaitGroup
-	wait.Add(1)
-
-	// vl provides a rwmutex for control concurrent reads and writes on the visited
-	// map.
-	var vl sync.RWMutex
-
-	// visited is a map for storing visited uri's to avoid visit loops.
-	visited := make(map[string]bool)
-	// visited[path.String()] = true
-
-	pl.Do("collectFrom", &pathBot{
-		config:    c,
-		path:      path.String(),
-		index:     path,
-		dead:      dead,
-		wait:      &wait,
-		vl:        &vl,
-		visited:   visited,
-		pool:      pl,
-		externals: c.All,
-		skipCheck: true,
-		maxdepths: c.Depth,
-	})
+	if cs.frontier == nil {
+		cs.frontier = NewMemoryFrontier(c.Priority)
+	}
 
-	wait.Wait()
+	if cs.visited == nil {
+		cs.visited = NewMemoryVisited()
+	}
 
-	return
+	for _, sd := range seeds {
+		path := sd.url.String()
+
+		if cs.visited.Visit(path) {
+			continue
+		}
+
+		// Evalue the giving path and check if its a crawlable endpoint and
+		// if the status meets our criteria.
+		status, crawleable, err := evaluatePath(path, c)
+		if err != nil {
+			if budget.refused(err) {
+				budget.skip()
+				continue
+			}
+
+			report := newLinkReport(path, "", status, err)
+			report.Site = sd.site
+			dead <- report
+			continue
+		}
+
+		if !crawleable {
+			continue
+		}
+
+		cs.push("collectFrom", FrontierEntry{URL: path, Site: sd.site, Checked: true})
+	}
+
+	workers := c.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Crawls cut short by a budget or a depth wait on each round, as which
+	// pages they crawl depends on the order pages are found in. Others pop
+	// pages as workers free up, so a slow page holds up no more than its
+	// own worker.
+	rounds := c.Budget != (Budget{}) || c.Depth > 0
+
+	cs.freed = make(chan struct{}, workers)
+
+	var crawling int
+	for {
+
+		// Rounds hold no more pages than are left within the budget, so the
+		// pages crawled are the best pending rather than the first fetched.
+		size := workers
+		switch left := budget.pagesLeft(); {
+		case left == 0:
+			cs.unexplored()
+			size = 0
+		case left > 0 && left < size:
+			size = left
+		}
+
+		for crawling < size {
+			entry, ok := cs.frontier.Pop()
+			if !ok {
+				break
+			}
+
+			crawling++
+
+			pl.Do("collectFrom", &pathBot{
+				crawlState: &cs,
+				path:       entry.URL,
+				source:     entry.Source,
+				link:       entry.link(),
+				site:       entry.Site,
+				depth:      entry.Depth,
+				skipCheck:  entry.Checked,
+			})
+		}
+
+		// Workers push the pages they find before they are done, so with no
+		// page crawling the frontier holds none left.
+		if crawling == 0 {
+			return
+		}
+
+		<-cs.freed
+		crawling--
+
+		for rounds && crawling > 0 {
+			<-cs.freed
+			crawling--
+		}
+	}
 }
 
 //==============================================================================
 
+// crawlState defines the state shared by the workers of a crawl.
+type crawlState struct {
+	config     *Config
+	seeds      []seed
+	dead       chan LinkReport
+	frontier   Frontier
+	visited    VisitedSet
+	secure     *secureProbe
+	inspectors []PageInspector
+	graph      *linkGraph
+	budget     *crawlBudget
+	externals  *externalChecker
+	maxdepth   int
+
+	// freed receives a value as each worker is done with its page.
+	freed chan struct{}
+}
+
+// push adds the giving page to the frontier, the crawl goes on without it if
+// the frontier fails to hold it.
+func (cs *crawlState) push(context interface{}, entry FrontierEntry) {
+	if err := cs.frontier.Push(entry); err != nil {
+		cs.config.Events.ErrorEvent(context, "push", err, "URL[%s]", entry.URL)
+	}
+}
+
+// unexplored leaves the pages pending unexplored once the page budget is
+// spent, without checking any of them.
+func (cs *crawlState) unexplored() {
+	for {
+		if _, ok := cs.frontier.Pop(); !ok {
+			return
+		}
+
+		cs.budget.exhaust(BudgetPages)
+		cs.budget.skip()
+	}
+}
+
+// done marks the page of a worker as crawled, freeing the worker for the next
+// page pending.
+func (cs *crawlState) done() {
+	cs.freed <- struct{}{}
+}
+
 // pathBot provides a worker which checks a giving URL path, cascading its
-// effects down its subroots and rescheduling new workers for those sublinks.
+// effects down its subroots and pushing the sublinks to crawl to the frontier.
 // It implements pool.Work interface.
 type pathBot struct {
+	*crawlState
 	path      string
-	dead      chan LinkReport
-	config    *Config
-	wait      *sync.WaitGroup
-	vl        *sync.RWMutex
-	visited   map[string]bool
-	pool      *pool.Pool
-	index     *url.URL
+	source    string
+	link      pageLink
+	site      string
+	depth     int
 	skipCheck bool
-	externals bool
-	maxdepths int
-	cd        int
 }
 
-// Work performs the necessary tasks of validating a link and rescheduling
-// checks for sublinks.
+// Work performs the necessary tasks of validating a link and pushing the
+// sublinks to crawl to the frontier.
 func (p *pathBot) Work(context interface{}, id int) {
-	defer p.wait.Done()
+	defer p.done()
 
-	// Prefilter checks, if we schedule this path and got resolved then ignore.
-	p.vl.RLock()
-	goOn := p.visited[p.path]
-	p.vl.RUnlock()
-
-	if goOn {
+	// Pages still pending once the budget is spent are left unexplored.
+	if p.budget.spent() {
+		p.budget.skip()
 		return
 	}
 
-	p.vl.Lock()
-	p.visited[p.path] = true
-	p.vl.Unlock()
-
 	if !p.skipCheck {
 		status, crawleable, err := evaluatePath(p.path, p.config)
 		if err != nil {
-			p.dead <- LinkReport{Link: p.path, Status: status, Error: err}
+			if p.budget.refused(err) {
+				p.budget.skip()
+				return
+			}
+
+			p.report(p.link.describe(newLinkReport(p.path, p.source, status, err)))
 			return
 		}
 
@@ -186,107 +604,172 @@ func (p *pathBot) Work(context interface{}, id int) {
 		}
 	}
 
-	links := make(chan string)
+	if !p.budget.page() {
+		p.budget.skip()
+		return
+	}
+
+	links := make(chan pageLink)
+
+	// Only inspectors need the document of a page.
+	pg, truncated, err := farmLinks(p.path, p.config, links, len(p.inspectors) > 0)
+	if err != nil {
+		if p.budget.refused(err) {
+			p.budget.skip()
+			return
+		}
 
-	if err := farmLinks(p.path, links); err != nil {
 		// fmt.Printf("Spidy Failed to Farm Links for Page[%s]: Error[%s]\n", p.path, err.Error())
-		p.dead <- LinkReport{Link: p.path, Status: http.StatusInternalServerError, Error: err}
+		p.report(p.link.describe(newLinkReport(p.path, p.source, http.StatusInternalServerError, err)))
 		return
 	}
 
+	// Links past the limit of a body are not found, so it is reported.
+	if truncated > 0 {
+		status := http.StatusOK
+		if pg != nil {
+			status = pg.Status
+		}
+
+		report := newLinkReport(p.path, p.source, status, fmt.Errorf("Body truncated at its limit of %d bytes", truncated))
+		report.Kind = KindTruncated
+		report.Severity = SeverityWarning
+		p.report(p.link.describe(report))
+	}
+
+	if pg != nil && p.graph != nil {
+		p.graph.page(p.path)
+	}
+
+	if pg != nil {
+		for _, report := range inspectPage(context, p.inspectors, pg) {
+			// Findings about the page itself are tagged with the link it was
+			// found by, unless they tag an element within the page.
+			if report.Source == "" {
+				report.Source = p.source
+
+				if report.Tag == "" {
+					report = p.link.describe(report)
+				}
+			}
+
+			p.report(report)
+		}
+	}
+
+	// Pages are https or not as served, after any redirects.
+	served := p.path
+	if pg != nil {
+		served = pg.URL.String()
+	}
+
 	for {
 		select {
-		case link, ok := <-links:
+		case pl, ok := <-links:
 			if !ok {
-				atomic.AddInt64(&depths, 1)
 				return
 			}
 
-			if p.maxdepths > 0 && int(atomic.LoadInt64(&depths)) > p.maxdepths {
-				return
+			// Mixed content is reported for every page it's found on, even if
+			// the link itself was already checked.
+			if report, ok := p.secure.checkMixed(served, pl); ok {
+				p.report(report)
 			}
 
-			p.vl.RLock()
-			found := p.visited[link]
-			p.vl.RUnlock()
-
-			// fmt.Printf("Check: %+s %t\n", link, found)
-			if found {
+			// Links are resolved against the page they were found in, so only
+			// need parsing.
+			pathURI, err := url.Parse(pl.URL)
+			if err != nil {
 				continue
 			}
 
-			// If its a hash based path, then just add it as seen and skip.
-			if strings.HasPrefix(link, "#") {
-				p.vl.Lock()
-				p.visited[link] = true
-				p.vl.Unlock()
-				continue
+			link := pathURI.String()
+			internal := inScope(p.seeds, pathURI)
+
+			// Every link of the page is part of the graph, even if it was
+			// already checked.
+			if p.graph != nil {
+				p.graph.link(p.path, pl, link, internal)
 			}
 
-			// If we are running into the same root link, skip it.
-			if strings.TrimSpace(link) == "/" || link == p.index.Path {
+			// If we are crawling within a session, avoid links which could end
+			// it.
+			if p.config.Client.Jar != nil && logoutPattern.MatchString(link) {
 				continue
 			}
 
-			pathURI, err := parsePath(link, p.index)
-			if err != nil {
+			// Check and mark the link as visited at once, we dont, want to go
+			// through the same link twice.
+			if p.visited.Visit(link) {
 				continue
 			}
 
-			// fmt.Printf("2ndCheck: %+s %t\n", pathURI.Path, found)
-			p.vl.RLock()
-			ok2 := p.visited[pathURI.Path]
-			p.vl.RUnlock()
-
-			if ok2 {
+			// If we are are not allowed external links, then check and if not
+			// within scope then skip.
+			if p.externals == nil && !internal {
 				continue
 			}
 
-			// If we are are not allowed external links, then check and if not
-			// within host then skip but add it to scene list, we dont, want
-			// to go through the same link twice.
-			if !p.externals && !strings.Contains(pathURI.Host, p.index.Host) {
-				p.vl.Lock()
-				p.visited[link] = true
-				p.visited[pathURI.Path] = true
-				p.vl.Unlock()
+			// Links found once the budget is spent are left unexplored.
+			if p.budget.spent() {
+				p.budget.skip()
 				continue
 			}
 
-			// Although we have fixed the path, we still need to add it down into the list
-			// of visited.
-			p.vl.Lock()
-			p.visited[link] = true
-			p.visited[pathURI.Path] = true
-			p.vl.Unlock()
+			// External links are only checked, on the pool of their own.
+			if !internal {
+				p.externals.check(context, newEntry(link, pl, p.path, p.site, p.depth+1))
+				continue
+			}
 
 			// To avoid lunching a worker for a non-crawlable link, we need to eval
 			// the link here.
-			_, crawleable, _ := evaluatePath(pathURI.String(), p.config)
+			status, crawleable, err := evaluatePath(pathURI.String(), p.config)
+			if err != nil {
+				if p.budget.refused(err) {
+					p.budget.skip()
+					continue
+				}
+
+				p.report(pl.describe(newLinkReport(pathURI.String(), p.path, status, err)))
+				continue
+			}
+
+			// A resource which answers but is not what the page loads it as,
+			// such as a login page in place of an image, is still broken.
+			if resource := resourceType(pl); p.config.Integrity && resource != "" {
+				if status, err := checkResource(link, resource, p.config); err != nil && !p.budget.refused(err) {
+					report := newLinkReport(link, p.path, status, err)
+					report.Kind = KindIntegrity
+					p.report(pl.describe(report))
+					continue
+				}
+			}
+
+			// Links of pages at the maximum depth are only checked, never
+			// crawled.
 			if !crawleable {
 				continue
 			}
 
-			p.wait.Add(1)
-
-			// fmt.Printf("Scheduling: %+s \n", pathURI.Path)
-			p.pool.Do(context, &pathBot{
-				path:      pathURI.String(),
-				config:    p.config,
-				index:     p.index,
-				dead:      p.dead,
-				wait:      p.wait,
-				vl:        p.vl,
-				visited:   p.visited,
-				pool:      p.pool,
-				externals: p.externals,
-				maxdepths: p.maxdepths,
-			})
+			if p.maxdepth > 0 && p.depth >= p.maxdepth {
+				continue
+			}
+
+			// Pages belong to the site whose scope they are within, which
+			// need not be the site of the page that found them.
+			p.push(context, newEntry(link, pl, p.path, siteOf(p.seeds, link), p.depth+1))
 		}
 	}
 
 }
 
+// report sends the giving finding, attributed to the site of the bot.
+func (p *pathBot) report(r LinkReport) {
+	r.Site = p.site
+	p.dead <- r
+}
+
 //==============================================================================
 
 // evaluatePath evalutes the giving URI path if valid and returns the status,
@@ -298,14 +781,15 @@ func evaluatePath(path string, c *Config) (status int, shouldCrawl bool, err err
 	res, err = c.Client.Head(path)
 	if err != nil {
 
-		// When an erro occurs, we get a nil response, so we have to print this out
-		// and designated this as a failure and a dead link.
-		fmt.Printf(`
-URL: %s
-Status: Failed to get HEAD for path
-Error: %s
+		// Requests refused by the budget of the crawl are not failures.
+		if errors.Is(err, errBudgetExhausted) {
+			return
+		}
 
-`, path, err.Error())
+		// When an erro occurs, we get a nil response, so we have to log this out
+		// and designated this as a failure and a dead link. Progress is only
+		// ever logged through the events, as stdout may carry the report.
+		c.Events.ErrorEvent("spidy", "evaluatePath", err, "URL[%s] : Failed to get HEAD for path", path)
 
 		status = http.StatusInternalServerError
 		return
@@ -318,16 +802,14 @@ Error: %s
 		return
 	}
 
-	if !strings.Contains(res.Header.Get("Content-Type"), "text/html") {
+	// Only pages and stylesheets contain links to crawl.
+	contentType := res.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "text/html") && !isStylesheet(contentType) {
 		status = res.StatusCode
 		return
 	}
 
-	fmt.Printf(`
-URL: %s
-Status Code: %d
-
-`, path, res.StatusCode)
+	c.Events.Event("spidy", "evaluatePath", "URL[%s] : Status Code[%d]", path, res.StatusCode)
 
 	shouldCrawl = true
 	return
@@ -347,62 +829,159 @@ func getAttr(attrs []html.Attribute, key string) (attr html.Attribute, found boo
 	return
 }
 
+// pageLink defines a link found within a page with the element and attribute
+// it was found in and what it is referenced as.
+type pageLink struct {
+	URL       string
+	Element   string
+	Attr      string
+	Rel       string
+	Reference string
+}
+
+// tag returns the element and attribute the link was found in, e.g
+// img[srcset].
+func (l pageLink) tag() string {
+	if l.Element == "" {
+		return ""
+	}
+
+	return l.Element + "[" + l.Attr + "]"
+}
+
+// describe returns the giving report tagged with where the link was found.
+func (l pageLink) describe(r LinkReport) LinkReport {
+	r.Tag = l.tag()
+	r.Reference = l.Reference
+	return r
+}
+
 // farmLinks takes a given url and retrieves the needed links associated with
-// that URL.
-func farmLinks(url string, port chan string) error {
-	doc, err := goquery.NewDocument(url)
+// that URL, returning the page they were found in if it is an HTML page. Pages
+// are only parsed into a document if dom is set, else their links are
+// extracted as they are read. Bodies are read up to the limit for their
+// content type, which is returned if the body was cut short at it.
+func farmLinks(url string, c *Config, port chan pageLink, dom bool) (*Page, int64, error) {
+	res, err := c.Client.Get(url)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
 
-	// Collect all href links within the document. This way we can capture
-	// external,internal and stylesheets within the page.
-	hrefs := doc.Find("[href]")
-	srcs := doc.Find("[src]")
+	body := limitBody(res, c)
 
-	hrefLen := hrefs.Length()
-	srcLen := srcs.Length()
+	var pg *Page
+	var links []pageLink
 
-	total := hrefLen
+	switch {
+	case isStylesheet(res.Header.Get("Content-Type")):
+		links, err = stylesheetLinks(res)
 
-	if total < srcLen {
-		total = srcLen
+	case dom:
+		pg = &Page{Link: url, URL: res.Request.URL, Status: res.StatusCode, Header: res.Header}
+		links, pg.Document, err = documentLinks(res)
+
+	default:
+		pg = &Page{Link: url, URL: res.Request.URL, Status: res.StatusCode, Header: res.Header}
+		links = streamLinks(res.Body, res.Request.URL)
+		res.Body.Close()
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var truncated int64
+	if body.truncated {
+		truncated = body.limit
 	}
 
 	go func() {
 		defer close(port)
 
-		for i := 0; i < total; i++ {
-			if i < hrefLen {
-				item, ok := getAttr(hrefs.Get(i).Attr, "href")
-				if !ok {
-					continue
-				}
+		for _, link := range links {
+			port <- link
+		}
+	}()
 
-				if strings.Contains(item.Val, "javascript:void(0)") {
-					continue
-				}
+	return pg, truncated, nil
+}
 
-				port <- item.Val
-			}
+// stylesheetLinks returns the links within the stylesheet of the giving
+// response, which are relative to the stylesheet itself.
+func stylesheetLinks(res *http.Response) ([]pageLink, error) {
+	defer res.Body.Close()
 
-			if i < srcLen {
-				item, ok := getAttr(srcs.Get(i).Attr, "src")
-				if !ok {
-					continue
-				}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
 
-				if strings.Contains(item.Val, "javascript:void(0)") {
-					continue
-				}
+	links := cssLinks(string(body), "stylesheet", "url()")
+	return resolveLinks(links, res.Request.URL), nil
+}
 
-				port <- item.Val
-			}
+// documentLinks returns the links within the attributes, <style> blocks and
+// style attributes of the document of the giving response. Links are resolved
+// against the document's <base href> if it has one, else the URL of the
+// document after any redirects. The parsed document is returned along with
+// its links.
+func documentLinks(res *http.Response) ([]pageLink, *goquery.Document, error) {
+	doc, err := goquery.NewDocumentFromResponse(res)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var links []pageLink
+
+	doc.Find("style").Each(func(_ int, s *goquery.Selection) {
+		links = append(links, cssLinks(s.Text(), "style", "url()")...)
+	})
+
+	doc.Find("[style]").Each(func(_ int, s *goquery.Selection) {
+		style, _ := s.Attr("style")
+		links = append(links, cssLinks(style, goquery.NodeName(s), "style")...)
+	})
+
+	links = append(links, extractLinks(doc)...)
+
+	return resolveLinks(links, documentBase(doc, res.Request.URL)), doc, nil
+}
+
+// documentBase returns the URL links within the document are relative to,
+// which is the first <base href> resolved against the document's URL if the
+// document has one.
+func documentBase(doc *goquery.Document, page *url.URL) *url.URL {
+	href, ok := doc.Find("base[href]").First().Attr("href")
+	if !ok {
+		return page
+	}
+
+	base, err := parsePath(strings.TrimSpace(href), page)
+	if err != nil {
+		return page
+	}
+
+	return base
+}
+
+// resolveLinks resolves the giving links against the base URL, dropping any
+// fragments as they refer to the same resource, and links which are invalid.
+func resolveLinks(links []pageLink, base *url.URL) []pageLink {
+	resolved := links[:0]
+
+	for _, link := range links {
+		uri, err := parsePath(link.URL, base)
+		if err != nil {
+			continue
 		}
 
-	}()
+		uri.Fragment = ""
+		link.URL = uri.String()
+
+		resolved = append(resolved, link)
+	}
 
-	return nil
+	return resolved
 }
 
 //==============================================================================
@@ -2,8 +2,10 @@ package spidy_test
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -93,6 +95,7 @@ func TestSpidy(t *testing.T) {
 		testInvalidScripts(conf, t)
 		testInvalidLinks(conf, t)
 		testInvalidImages(conf, t)
+		testQuietCrawl(conf, t)
 	}
 }
 
@@ -177,4 +180,37 @@ func testInvalidLinks(c spidy.Config, t *testing.T) {
 	}
 }
 
+// testQuietCrawl tests crawling a page writes nothing to stdout, which may
+// carry the report of the crawl.
+func testQuietCrawl(c spidy.Config, t *testing.T) {
+	t.Logf("\tWhen crawling a page with invalid hyperlinks")
+	{
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a pipe: %q", tests.Failed, err)
+		}
+
+		stdout := os.Stdout
+		os.Stdout = w
+
+		c.URL = fmt.Sprintf("%s?page=badlinks", c.URL)
+		_, err = spidy.Run(context, &c)
+
+		os.Stdout = stdout
+		w.Close()
+
+		written, _ := ioutil.ReadAll(r)
+		r.Close()
+
+		if err != nil {
+			t.Fatalf("\t%s\tShould have successfully retrieved page[%s]: %q", tests.Failed, c.URL, err)
+		}
+
+		if len(written) != 0 {
+			t.Fatalf("\t%s\tShould have written nothing to stdout but got: %s", tests.Failed, written)
+		}
+		t.Logf("\t%s\tShould have written nothing to stdout", tests.Success)
+	}
+}
+
 //==============================================================================
@@ -0,0 +1,158 @@
+package spidy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// dateLayout defines the layout of the expiry dates of suppressions.
+const dateLayout = "2006-01-02"
+
+// Suppression defines a known or accepted failure whose findings are demoted
+// to the suppressed severity until it expires. URL and Source are patterns
+// where * matches any run of characters, Source, Status and Kind are optional
+// and narrow which findings of the URL are suppressed.
+type Suppression struct {
+	URL     string `json:"url"`
+	Source  string `json:"source,omitempty"`
+	Status  int    `json:"status,omitempty"`
+	Kind    string `json:"kind,omitempty"`
+	Expires string `json:"expires"` // Date (YYYY-MM-DD) after which the suppression no longer applies.
+	Reason  string `json:"reason"`
+}
+
+// matches reports whether the suppression applies to the giving finding.
+func (s Suppression) matches(l LinkReport) bool {
+	if !matchGlob(s.URL, l.Link) {
+		return false
+	}
+
+	if s.Source != "" && !matchGlob(s.Source, l.Source) {
+		return false
+	}
+
+	if s.Status != 0 && s.Status != l.Status {
+		return false
+	}
+
+	if s.Kind != "" && s.Kind != l.Kind {
+		return false
+	}
+
+	return true
+}
+
+// expired reports whether the suppression has expired by the giving time,
+// suppressions with an invalid expiry date are considered expired.
+func (s Suppression) expired(now time.Time) bool {
+	expires, err := time.Parse(dateLayout, s.Expires)
+	if err != nil {
+		return true
+	}
+
+	// Suppressions apply through the whole of their expiry date.
+	return !now.Before(expires.AddDate(0, 0, 1))
+}
+
+// LoadSuppressions reads the JSON suppression file at the giving path.
+//
+//	{
+//	  "suppressions": [
+//	    {"url": "https://www.linkedin.com/*", "status": 999, "expires": "2027-01-01", "reason": "Blocks bots"},
+//	    {"url": "https://example.com/old/*", "source": "https://example.com/archive/*", "expires": "2026-12-01", "reason": "Archive is frozen"}
+//	  ]
+//	}
+func LoadSuppressions(path string) ([]Suppression, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var sf struct {
+		Suppressions []Suppression `json:"suppressions"`
+	}
+
+	if err := json.NewDecoder(file).Decode(&sf); err != nil {
+		return nil, fmt.Errorf("Invalid suppressions[%s] : %s", path, err)
+	}
+
+	for _, s := range sf.Suppressions {
+		if s.URL == "" {
+			return nil, fmt.Errorf("Invalid suppressions[%s] : Suppressions require a url", path)
+		}
+
+		if _, err := time.Parse(dateLayout, s.Expires); err != nil {
+			return nil, fmt.Errorf("Invalid suppressions[%s] : URL[%s] requires an expiry date as YYYY-MM-DD", path, s.URL)
+		}
+
+		if s.Reason == "" {
+			return nil, fmt.Errorf("Invalid suppressions[%s] : URL[%s] requires a reason", path, s.URL)
+		}
+	}
+
+	return sf.Suppressions, nil
+}
+
+// suppress demotes the findings of the report which match any of the giving
+// suppressions to the suppressed severity. Suppressions which expired by the
+// giving time no longer apply and are reported as warnings instead.
+func (r *Report) suppress(suppressions []Suppression, now time.Time) {
+	var active []Suppression
+
+	for _, s := range suppressions {
+		if !s.expired(now) {
+			active = append(active, s)
+			continue
+		}
+
+		r.Links = append(r.Links, LinkReport{
+			Link:     s.URL,
+			Source:   s.Source,
+			Status:   s.Status,
+			Error:    fmt.Errorf("Suppression expired on %s : %s", s.Expires, s.Reason),
+			Kind:     KindExpiredSuppression,
+			Severity: SeverityWarning,
+		})
+	}
+
+	for i, link := range r.Links {
+		for _, s := range active {
+			if s.matches(link) {
+				r.Links[i].Severity = SeveritySuppressed
+				break
+			}
+		}
+	}
+}
+
+// matchGlob reports whether the text matches the giving pattern, where *
+// matches any run of characters including none.
+func matchGlob(pattern string, text string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == text
+	}
+
+	if !strings.HasPrefix(text, parts[0]) {
+		return false
+	}
+
+	text = text[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(text, part)
+		if i < 0 {
+			return false
+		}
+
+		text = text[i+len(part):]
+	}
+
+	return len(text) >= len(last) && strings.HasSuffix(text, last)
+}
@@ -0,0 +1,111 @@
+package spidy_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// TestSuppressions tests demoting the findings of known failures.
+func TestSuppressions(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to suppress known or accepted failures")
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/in/profile":
+				res.WriteHeader(999)
+			default:
+				res.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		defer server.Close()
+
+		file, err := ioutil.TempFile("", "spidy-suppressions")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a suppression file: %q", tests.Failed, err)
+		}
+		defer os.Remove(file.Name())
+
+		fmt.Fprintf(file, `{"suppressions": [
+			{"url": "%s/in/*", "status": 999, "expires": "2999-01-01", "reason": "Blocks bots"},
+			{"url": "%s/old/*", "source": "archive", "expires": "2000-01-01", "reason": "Archive is frozen"}
+		]}`, server.URL, server.URL)
+		file.Close()
+
+		t.Logf("\tWhen checking links with a suppression file")
+		{
+			suppressions, err := spidy.LoadSuppressions(file.Name())
+			if err != nil {
+				t.Fatalf("\t%s\tShould have loaded the suppressions: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have loaded the suppressions", tests.Success)
+
+			conf := spidy.Config{
+				Client:       &http.Client{Timeout: 30 * time.Second},
+				Workers:      10,
+				Events:       events,
+				Suppressions: suppressions,
+			}
+
+			links := []spidy.CheckLink{
+				{URL: server.URL + "/in/profile", Source: "team"},
+				{URL: server.URL + "/old/page", Source: "archive"},
+			}
+
+			report, err := spidy.Check(context, &conf, links)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have checked the links: %q", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have checked the links", tests.Success)
+
+			severities := make(map[string]spidy.Severity)
+			for _, f := range report.Links {
+				severities[f.Kind+" "+f.Link] = f.Severity
+			}
+
+			if s := severities[spidy.KindDeadLink+" "+server.URL+"/in/profile"]; s != spidy.SeveritySuppressed {
+				t.Errorf("\t%s\tShould have suppressed the matching finding but got %q", tests.Failed, s)
+			} else {
+				t.Logf("\t%s\tShould have suppressed the matching finding", tests.Success)
+			}
+
+			if s := severities[spidy.KindDeadLink+" "+server.URL+"/old/page"]; s != spidy.SeverityError {
+				t.Errorf("\t%s\tShould not have applied the expired suppression but got %q", tests.Failed, s)
+			} else {
+				t.Logf("\t%s\tShould not have applied the expired suppression", tests.Success)
+			}
+
+			if s := severities[spidy.KindExpiredSuppression+" "+server.URL+"/old/*"]; s != spidy.SeverityWarning {
+				t.Errorf("\t%s\tShould have flagged the expired suppression but got %q", tests.Failed, s)
+			} else {
+				t.Logf("\t%s\tShould have flagged the expired suppression", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen loading a suppression without an expiry date")
+		{
+			if err := ioutil.WriteFile(file.Name(), []byte(`{"suppressions": [{"url": "*", "reason": "Everything"}]}`), 0644); err != nil {
+				t.Fatalf("\t%s\tShould be able to write the suppression file: %q", tests.Failed, err)
+			}
+
+			if _, err := spidy.LoadSuppressions(file.Name()); err == nil {
+				t.Errorf("\t%s\tShould have failed to load the suppressions", tests.Failed)
+			} else {
+				t.Logf("\t%s\tShould have failed to load the suppressions", tests.Success)
+			}
+		}
+	}
+}
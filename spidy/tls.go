@@ -0,0 +1,299 @@
+package spidy
+
+import (
+	gocontext "context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CertReport defines the TLS certificate details of a HTTPS host contacted
+// during the crawl.
+type CertReport struct {
+	Host      string
+	Issuer    string
+	Subject   string
+	NotBefore time.Time
+	NotAfter  time.Time
+	DNSNames  []string
+	HostMatch bool
+	Insecure  bool
+	Error     error
+}
+
+//==============================================================================
+
+// tlsAudit records the certificates of every HTTPS host contacted, verifying
+// them against the configured roots unless the host is allowed to be insecure.
+type tlsAudit struct {
+	roots    *x509.CertPool
+	insecure []string
+
+	ml    sync.Mutex
+	certs map[string]*CertReport
+}
+
+// newTLSAudit returns a new tlsAudit for the giving config, loading the custom
+// CA bundle if one is configured.
+func newTLSAudit(c *Config) (*tlsAudit, error) {
+	a := tlsAudit{
+		insecure: c.InsecureHosts,
+		certs:    make(map[string]*CertReport),
+	}
+
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		// Custom CAs are trusted in addition to the system's, as only internal
+		// hosts are expected to use them.
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in CA file[%s]", c.CAFile)
+		}
+
+		a.roots = roots
+	}
+
+	return &a, nil
+}
+
+// client returns a copy of the giving client whose transport verifies the
+// certificates of HTTPS hosts through the audit. Clients with a transport
+// other than a *http.Transport are copied with their transport as is and
+// their certificates are not audited, unless CAs or insecure hosts are
+// configured which only an audited transport can apply.
+func (a *tlsAudit) client(client *http.Client) (*http.Client, error) {
+	var transport *http.Transport
+
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		if a.roots != nil || len(a.insecure) > 0 {
+			return nil, errors.New("CA files and insecure hosts require the Client to use a *http.Transport")
+		}
+
+		c := *client
+		return &c, nil
+	}
+
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+
+	// A transport which already skipped verification skips it for every host.
+	if transport.TLSClientConfig.InsecureSkipVerify {
+		a.insecure = []string{"*"}
+	}
+
+	if a.roots != nil {
+		transport.TLSClientConfig.RootCAs = a.roots
+	} else {
+		a.roots = transport.TLSClientConfig.RootCAs
+	}
+
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+
+	// The handshake is done here rather than by the transport, as the host
+	// being dialed is needed to verify the certificate and isn't known to the
+	// tls.Config callbacks for IP addresses.
+	transport.DialTLSContext = func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg := transport.TLSClientConfig.Clone()
+		if cfg.ServerName == "" {
+			cfg.ServerName = host
+		}
+
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return a.verify(host, cs)
+		}
+
+		tc := tls.Client(conn, cfg)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return tc, nil
+	}
+
+	cl := *client
+	cl.Transport = transport
+
+	return &cl, nil
+}
+
+// verify records the certificate of the host the connection was made to,
+// failing the connection if it could not be verified.
+func (a *tlsAudit) verify(host string, cs tls.ConnectionState) error {
+	a.ml.Lock()
+	defer a.ml.Unlock()
+
+	cert, ok := a.certs[host]
+	if !ok {
+		cert = a.inspect(host, cs)
+		a.certs[host] = cert
+	}
+
+	if cert.Insecure {
+		return nil
+	}
+
+	return cert.Error
+}
+
+// inspect verifies the peer certificates of the giving connection, returning
+// the details of the leaf certificate.
+func (a *tlsAudit) inspect(host string, cs tls.ConnectionState) *CertReport {
+	cert := CertReport{
+		Host:     host,
+		Insecure: a.isInsecure(host),
+	}
+
+	if len(cs.PeerCertificates) == 0 {
+		cert.Error = CertError{Host: host, Err: errors.New("No certificates presented")}
+		return &cert
+	}
+
+	leaf := cs.PeerCertificates[0]
+
+	cert.Issuer = leaf.Issuer.String()
+	cert.Subject = leaf.Subject.String()
+	cert.NotBefore = leaf.NotBefore
+	cert.NotAfter = leaf.NotAfter
+	cert.DNSNames = leaf.DNSNames
+	cert.HostMatch = leaf.VerifyHostname(host) == nil
+
+	intermediates := x509.NewCertPool()
+	for _, c := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+
+	opts := x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         a.roots,
+		Intermediates: intermediates,
+	}
+
+	if _, err := leaf.Verify(opts); err != nil {
+		cert.Error = CertError{Host: host, Err: err}
+	}
+
+	return &cert
+}
+
+// isInsecure reports whether the host matches any of the insecure host
+// patterns.
+func (a *tlsAudit) isInsecure(host string) bool {
+	for _, pattern := range a.insecure {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// report returns the certificates recorded sorted by host, with the findings
+// for certificates expiring within the giving days and for insecure hosts
+// whose certificates failed verification.
+func (a *tlsAudit) report(days int) ([]CertReport, []LinkReport) {
+	a.ml.Lock()
+	defer a.ml.Unlock()
+
+	var certs []CertReport
+	var findings []LinkReport
+
+	for _, cert := range a.certs {
+		certs = append(certs, *cert)
+
+		link := "https://" + cert.Host
+
+		if cert.Error != nil {
+			if cert.Insecure {
+				findings = append(findings, LinkReport{Link: link, Kind: KindCertificate, Severity: SeverityWarning, Error: cert.Error})
+			}
+			continue
+		}
+
+		if days <= 0 {
+			continue
+		}
+
+		left := cert.NotAfter.Sub(time.Now())
+		if left < time.Duration(days)*24*time.Hour {
+			err := fmt.Errorf("Certificate expires in %d days on %s", int(left.Hours()/24), cert.NotAfter.Format("2006-01-02"))
+			findings = append(findings, LinkReport{Link: link, Kind: KindCertExpiry, Severity: SeverityWarning, Error: err})
+		}
+	}
+
+	sort.Sort(certsByHost(certs))
+
+	return certs, findings
+}
+
+//==============================================================================
+
+// CertError defines an error for a certificate which failed verification.
+type CertError struct {
+	Host string
+	Err  error
+}
+
+// Error implements the error interface.
+func (c CertError) Error() string {
+	return fmt.Sprintf("Certificate for %s failed verification : %s", c.Host, c.Err)
+}
+
+// isCertError reports whether the giving error was caused by the failed
+// verification of a certificate.
+func isCertError(err error) bool {
+	var ce CertError
+	if errors.As(err, &ce) {
+		return true
+	}
+
+	// Without auditing the transport reports the x509 errors directly.
+	msg := err.Error()
+	return strings.Contains(msg, "x509:") || strings.Contains(msg, "tls: failed to verify")
+}
+
+//==============================================================================
+
+// certsByHost sorts certificates by their host.
+type certsByHost []CertReport
+
+func (c certsByHost) Len() int           { return len(c) }
+func (c certsByHost) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c certsByHost) Less(i, j int) bool { return c[i].Host < c[j].Host }
@@ -0,0 +1,157 @@
+package spidy_test
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+//==============================================================================
+
+// TestTLSAudit tests the auditing of the certificates of HTTPS hosts.
+func TestTLSAudit(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to audit the certificates of HTTPS hosts")
+	{
+		server := httptest.NewTLSServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.Write([]byte(`<html><body><a href="/about"></a></body></html>`))
+		}))
+
+		defer server.Close()
+
+		caFile := writeCAFile(server, t)
+		defer os.Remove(caFile)
+
+		conf := spidy.Config{
+			Client:  &http.Client{Timeout: 30 * time.Second},
+			URL:     server.URL,
+			Workers: 30,
+			Depth:   -1,
+			Events:  events,
+		}
+
+		t.Logf("\tWhen crawling a host with an untrusted certificate")
+		{
+			c := conf
+			report, err := spidy.Crawl(context, &c)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have successfully crawled page[%s]: %q", tests.Failed, c.URL, err)
+			}
+			t.Logf("\t%s\tShould have successfully crawled page[%s]", tests.Success, c.URL)
+
+			if len(report.Links) != 1 || report.Links[0].Kind != spidy.KindCertificate {
+				t.Fatalf("\t%s\tShould have reported a certificate finding: %+v", tests.Failed, report.Links)
+			}
+			t.Logf("\t%s\tShould have reported a certificate finding", tests.Success)
+
+			if len(report.Certs) != 1 || report.Certs[0].Error == nil {
+				t.Fatalf("\t%s\tShould have recorded the failed certificate: %+v", tests.Failed, report.Certs)
+			}
+			t.Logf("\t%s\tShould have recorded the failed certificate", tests.Success)
+		}
+
+		t.Logf("\tWhen crawling a host trusted through a CA file")
+		{
+			c := conf
+			c.CAFile = caFile
+			c.CertExpiry = 365 * 100
+
+			report, err := spidy.Crawl(context, &c)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have successfully crawled page[%s]: %q", tests.Failed, c.URL, err)
+			}
+			t.Logf("\t%s\tShould have successfully crawled page[%s]", tests.Success, c.URL)
+
+			if len(report.Certs) != 1 || report.Certs[0].Error != nil || !report.Certs[0].HostMatch || report.Certs[0].Issuer == "" {
+				t.Fatalf("\t%s\tShould have recorded a valid certificate: %+v", tests.Failed, report.Certs)
+			}
+			t.Logf("\t%s\tShould have recorded a valid certificate", tests.Success)
+
+			if len(report.Links) != 1 || report.Links[0].Kind != spidy.KindCertExpiry || report.Links[0].Severity != spidy.SeverityWarning {
+				t.Fatalf("\t%s\tShould have warned of the certificate expiry: %+v", tests.Failed, report.Links)
+			}
+			t.Logf("\t%s\tShould have warned of the certificate expiry", tests.Success)
+		}
+
+		t.Logf("\tWhen crawling a host allowed to be insecure")
+		{
+			c := conf
+			c.InsecureHosts = []string{"127.0.0.*"}
+
+			report, err := spidy.Crawl(context, &c)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have successfully crawled page[%s]: %q", tests.Failed, c.URL, err)
+			}
+			t.Logf("\t%s\tShould have successfully crawled page[%s]", tests.Success, c.URL)
+
+			if len(report.Links) != 1 || report.Links[0].Kind != spidy.KindCertificate || report.Links[0].Severity != spidy.SeverityWarning {
+				t.Fatalf("\t%s\tShould have only warned of the certificate: %+v", tests.Failed, report.Links)
+			}
+			t.Logf("\t%s\tShould have only warned of the certificate", tests.Success)
+		}
+
+		t.Logf("\tWhen crawling with a client wrapping its transport")
+		{
+			var requests int64
+			wrapped := server.Client().Transport
+
+			c := conf
+			c.Client = &http.Client{
+				Timeout: 30 * time.Second,
+				Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					atomic.AddInt64(&requests, 1)
+					return wrapped.RoundTrip(req)
+				}),
+			}
+
+			report, err := spidy.Crawl(context, &c)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have successfully crawled page[%s]: %q", tests.Failed, c.URL, err)
+			}
+			t.Logf("\t%s\tShould have successfully crawled page[%s]", tests.Success, c.URL)
+
+			if len(report.Links) != 0 || len(report.Certs) != 0 || atomic.LoadInt64(&requests) == 0 {
+				t.Fatalf("\t%s\tShould have crawled through the transport without auditing it: %+v", tests.Failed, report.Links)
+			}
+			t.Logf("\t%s\tShould have crawled through the transport without auditing it", tests.Success)
+
+			c.CAFile = caFile
+			if _, err := spidy.Crawl(context, &c); err == nil {
+				t.Fatalf("\t%s\tShould have failed to apply a CA file to the transport", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have failed to apply a CA file to the transport", tests.Success)
+		}
+	}
+}
+
+// roundTripperFunc adapts a function to the http.RoundTripper interface.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements the http.RoundTripper interface.
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// writeCAFile writes the certificate of the giving server into a PEM file
+// which can be used as a CA bundle, returning the path of the file.
+func writeCAFile(server *httptest.Server, t *testing.T) string {
+	file, err := ioutil.TempFile("", "spidy-ca")
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a CA file: %q", tests.Failed, err)
+	}
+
+	defer file.Close()
+
+	pem.Encode(file, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	return file.Name()
+}
@@ -0,0 +1,195 @@
+package spidy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultWatchKeep is the number of runs a Watch keeps by default.
+const DefaultWatchKeep = 10
+
+// runLayout is the layout of the start time of a run within the name of its
+// report, which sorts in the order runs started.
+const runLayout = "20060102T150405.000000000Z"
+
+// Watch defines a crawl re-run on a schedule, notifying only when the findings
+// of a run differ from those of the run before it.
+type Watch struct {
+	Schedule Schedule
+	Events   Events
+
+	// Windows confine runs to spans of the day, such as off-peak hours. Runs
+	// due outside all of them are put off until the first opens, and a run
+	// is not cut short when its window closes.
+	Windows []Window
+
+	// Dir keeps the JSON reports of the last Keep runs for inspection,
+	// defaulting to DefaultWatchKeep. The latest report within Dir is the
+	// one the first run is compared with, so restarting a watch doesn't
+	// notify of findings already known.
+	Dir  string
+	Keep int
+
+	// Notify is called with the findings newly broken or fixed by a run.
+	Notify func(context interface{}, diff *DiffReport) error
+}
+
+// Run runs the giving crawl on the schedule of the watch until the stop
+// channel is closed or the schedule has no runs left. The first run starts at
+// once, or once the first window opens.
+func (w *Watch) Run(context interface{}, crawl func() (*Report, error), stop <-chan struct{}) error {
+	if w.Schedule == nil {
+		return errors.New("No schedule given")
+	}
+
+	if w.Dir != "" {
+		if err := os.MkdirAll(w.Dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	previous := w.latest(context)
+
+	next := confine(w.Windows, time.Now())
+
+	for {
+		w.Events.Event(context, "Watch", "Next Run : %s", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-stop:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		started := time.Now()
+
+		// A failed run is logged and the watch goes on with the next.
+		report, err := crawl()
+		if err != nil {
+			w.Events.ErrorEvent(context, "Watch", err, "Run Failed")
+		} else {
+			w.record(context, previous, report)
+			previous = report
+		}
+
+		if next = nextRun(w.Schedule, w.Windows, started); next.IsZero() {
+			return nil
+		}
+	}
+}
+
+// record keeps the report of a run and notifies of how its findings differ
+// from those of the report of the run before it, if any.
+func (w *Watch) record(context interface{}, previous *Report, report *Report) {
+	if w.Dir != "" {
+		if err := w.save(report); err != nil {
+			w.Events.ErrorEvent(context, "Watch", err, "Saving Report")
+		}
+	}
+
+	if previous == nil {
+		previous = &Report{}
+	}
+
+	diff := Diff(previous, report)
+
+	w.Events.Event(context, "Watch", "Run Completed : Broken[%d] : Fixed[%d] : Still Broken[%d]", len(diff.Broken), len(diff.Fixed), len(diff.Still))
+
+	if !diff.Changed() || w.Notify == nil {
+		return
+	}
+
+	if err := w.Notify(context, diff); err != nil {
+		w.Events.ErrorEvent(context, "Watch", err, "Notifying")
+	}
+}
+
+// save writes the giving report to the directory of the watch, removing the
+// reports of runs past the number kept.
+func (w *Watch) save(report *Report) error {
+	started := report.Started
+	if started.IsZero() {
+		started = time.Now()
+	}
+
+	file, err := os.Create(filepath.Join(w.Dir, "spidy-"+started.UTC().Format(runLayout)+".json"))
+	if err != nil {
+		return err
+	}
+
+	if err := WriteReport(file, report, FormatJSON); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	keep := w.Keep
+	if keep <= 0 {
+		keep = DefaultWatchKeep
+	}
+
+	runs, err := w.runs()
+	if err != nil {
+		return err
+	}
+
+	for len(runs) > keep {
+		if err := os.Remove(runs[0]); err != nil {
+			return err
+		}
+
+		runs = runs[1:]
+	}
+
+	return nil
+}
+
+// runs returns the reports kept within the directory of the watch, oldest
+// first.
+func (w *Watch) runs() ([]string, error) {
+	runs, err := filepath.Glob(filepath.Join(w.Dir, "spidy-*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(runs)
+	return runs, nil
+}
+
+// latest returns the report of the latest run kept within the directory of
+// the watch, or nil if there is none.
+func (w *Watch) latest(context interface{}) *Report {
+	if w.Dir == "" {
+		return nil
+	}
+
+	runs, err := w.runs()
+	if err != nil || len(runs) == 0 {
+		return nil
+	}
+
+	file, err := os.Open(runs[len(runs)-1])
+	if err != nil {
+		w.Events.ErrorEvent(context, "Watch", err, "Reading Report")
+		return nil
+	}
+
+	defer file.Close()
+
+	report, err := ReadReport(file, FormatJSON)
+	if err != nil {
+		w.Events.ErrorEvent(context, "Watch", err, "Reading Report")
+		return nil
+	}
+
+	return report
+}
@@ -0,0 +1,179 @@
+package spidy_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+// TestSchedules tests when the runs of cron expressions and windows are due.
+func TestSchedules(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	at := func(value string) time.Time {
+		tm, err := time.Parse("2006-01-02 15:04", value)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to parse the time %s: %q", tests.Failed, value, err)
+		}
+
+		return tm
+	}
+
+	t.Logf("Given the need to schedule crawls with cron expressions")
+	{
+		schedules := []struct {
+			expr  string
+			after string
+			next  string
+		}{
+			{"*/15 * * * *", "2026-10-16 10:07", "2026-10-16 10:15"},
+			{"0 3 * * 1-5", "2026-10-16 04:00", "2026-10-19 03:00"},
+			{"0 0 29 2 *", "2026-01-01 00:00", "2028-02-29 00:00"},
+			{"0 0 13 * 5", "2026-10-01 00:00", "2026-10-02 00:00"},
+			{"30 22 * * 0", "2026-10-16 10:00", "2026-10-18 22:30"},
+			{"@daily", "2026-10-16 10:00", "2026-10-17 00:00"},
+		}
+
+		for _, s := range schedules {
+			t.Logf("\tWhen running %q after %s", s.expr, s.after)
+			{
+				schedule, err := spidy.ParseCron(s.expr)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to parse the expression: %q", tests.Failed, err)
+				}
+
+				if next := schedule.Next(at(s.after)); !next.Equal(at(s.next)) {
+					t.Errorf("\t%s\tShould run next at %s but got %s", tests.Failed, s.next, next)
+				} else {
+					t.Logf("\t%s\tShould run next at %s", tests.Success, s.next)
+				}
+			}
+		}
+
+		t.Logf("\tWhen parsing invalid expressions")
+		{
+			for _, expr := range []string{"61 * * * *", "* * *", "*/0 * * * *", "5-1 * * * *"} {
+				if _, err := spidy.ParseCron(expr); err == nil {
+					t.Errorf("\t%s\tShould have failed to parse %q", tests.Failed, expr)
+				} else {
+					t.Logf("\t%s\tShould have failed to parse %q", tests.Success, expr)
+				}
+			}
+		}
+	}
+
+	t.Logf("Given the need to confine crawls to off-peak hours")
+	{
+		t.Logf("\tWhen checking times against a window spanning midnight")
+		{
+			window, err := spidy.ParseWindow("22:00-06:00")
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to parse the window: %q", tests.Failed, err)
+			}
+
+			contains := window.Contains(at("2026-10-16 23:30")) && window.Contains(at("2026-10-16 05:59"))
+			if !contains || window.Contains(at("2026-10-16 06:00")) || window.Contains(at("2026-10-16 12:00")) {
+				t.Errorf("\t%s\tShould only contain the times within the window", tests.Failed)
+			} else {
+				t.Logf("\t%s\tShould only contain the times within the window", tests.Success)
+			}
+		}
+	}
+}
+
+// TestWatch tests re-running crawls on a schedule, notifying only when the
+// findings change.
+func TestWatch(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	dir, err := ioutil.TempDir("", "spidy-watch")
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a directory: %q", tests.Failed, err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	finding := func(link string) spidy.LinkReport {
+		return spidy.LinkReport{Link: link, Source: "http://example.com/", Status: 404, Kind: spidy.KindDeadLink, Severity: spidy.SeverityError}
+	}
+
+	// watch runs the giving sequence of findings, returning the diffs it was
+	// notified of.
+	watch := func(runs [][]spidy.LinkReport) []*spidy.DiffReport {
+		var diffs []*spidy.DiffReport
+
+		w := spidy.Watch{
+			Schedule: spidy.Every(10 * time.Millisecond),
+			Events:   events,
+			Dir:      dir,
+			Keep:     2,
+			Notify: func(context interface{}, diff *spidy.DiffReport) error {
+				diffs = append(diffs, diff)
+				return nil
+			},
+		}
+
+		stop := make(chan struct{})
+
+		var run int
+		crawl := func() (*spidy.Report, error) {
+			report := spidy.Report{Started: time.Now(), Finished: time.Now(), Links: runs[run]}
+
+			if run++; run == len(runs) {
+				close(stop)
+			}
+
+			return &report, nil
+		}
+
+		if err := w.Run(context, crawl, stop); err != nil {
+			t.Fatalf("\t%s\tShould be able to watch: %q", tests.Failed, err)
+		}
+
+		return diffs
+	}
+
+	a, b := finding("http://example.com/a"), finding("http://example.com/b")
+
+	t.Logf("Given the need to watch a site for changes")
+	{
+		t.Logf("\tWhen findings break, stay broken and are fixed")
+		{
+			diffs := watch([][]spidy.LinkReport{{a}, {a}, {a, b}, {b}})
+
+			switch {
+			case len(diffs) != 3:
+				t.Errorf("\t%s\tShould have notified of the 3 runs which changed but got %d", tests.Failed, len(diffs))
+			case len(diffs[1].Broken) != 1 || diffs[1].Broken[0].Link != b.Link:
+				t.Errorf("\t%s\tShould have notified of the newly broken link but got %+v", tests.Failed, diffs[1])
+			case len(diffs[2].Fixed) != 1 || diffs[2].Fixed[0].Link != a.Link:
+				t.Errorf("\t%s\tShould have notified of the fixed link but got %+v", tests.Failed, diffs[2])
+			default:
+				t.Logf("\t%s\tShould have notified only of the runs which changed", tests.Success)
+			}
+
+			runs, _ := filepath.Glob(filepath.Join(dir, "spidy-*.json"))
+			if len(runs) != 2 {
+				t.Errorf("\t%s\tShould have kept the last 2 runs but got %d", tests.Failed, len(runs))
+			} else {
+				t.Logf("\t%s\tShould have kept the last 2 runs", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen restarting the watch with the same findings")
+		{
+			if diffs := watch([][]spidy.LinkReport{{b}}); len(diffs) != 0 {
+				t.Errorf("\t%s\tShould not have notified of findings already known but got %d", tests.Failed, len(diffs))
+			} else {
+				t.Logf("\t%s\tShould not have notified of findings already known", tests.Success)
+			}
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ardanlabs/kit/cfg"
+	"github.com/ardanlabs/spidy/spidy"
+)
+
+// watchOptions defines the settings of spidy watch on top of those of the
+// crawl it runs.
+type watchOptions struct {
+	every   time.Duration
+	cron    string
+	windows listFlag
+	keep    int
+	dir     string
+	notify  string
+}
+
+// register registers the flags of the watch options on the giving flag set.
+func (w *watchOptions) register(fs *flag.FlagSet) {
+	fs.DurationVar(&w.every, "every", 0, "Interval to crawl on, e.g 6h")
+	fs.StringVar(&w.cron, "cron", "", "Cron expression to crawl on, e.g \"0 3 * * *\"")
+	fs.Var(&w.windows, "window", "Span of the day crawls are confined to, e.g 22:00-06:00, can be repeated")
+	fs.IntVar(&w.keep, "keep", spidy.DefaultWatchKeep, "Number of runs to keep the reports of")
+	fs.StringVar(&w.dir, "runs-dir", "spidy-runs", "Directory the reports of the runs are kept in")
+	fs.StringVar(&w.notify, "notify", "", "URL to post the changes of a run to as JSON")
+}
+
+// env overrides the watch options with any SPIDY_WATCH_ environment
+// variables set.
+func (w *watchOptions) env() {
+	if err := cfg.Init(cfg.EnvProvider{Namespace: "SPIDY"}); err != nil {
+		return
+	}
+
+	if ev, err := cfg.String("WATCH_EVERY"); err == nil {
+		if d, err := time.ParseDuration(ev); err == nil {
+			w.every = d
+		}
+	}
+
+	if cr, err := cfg.String("WATCH_CRON"); err == nil {
+		w.cron = cr
+	}
+
+	if wi, err := cfg.String("WATCH_WINDOW"); err == nil {
+		w.windows = strings.Split(wi, ",")
+	}
+
+	if kp, err := cfg.Int("WATCH_KEEP"); err == nil {
+		w.keep = kp
+	}
+
+	if wd, err := cfg.String("WATCH_DIR"); err == nil {
+		w.dir = wd
+	}
+
+	if wn, err := cfg.String("WATCH_NOTIFY"); err == nil {
+		w.notify = wn
+	}
+}
+
+// watch returns the watch of the options.
+func (w *watchOptions) watch() (*spidy.Watch, error) {
+	var schedule spidy.Schedule
+
+	switch {
+	case w.every > 0 && w.cron != "":
+		return nil, errors.New("Both an interval and a cron expression given")
+	case w.every > 0:
+		schedule = spidy.Every(w.every)
+	case w.cron != "":
+		cron, err := spidy.ParseCron(w.cron)
+		if err != nil {
+			return nil, err
+		}
+
+		schedule = cron
+	default:
+		return nil, errors.New("No interval or cron expression given")
+	}
+
+	var windows []spidy.Window
+	for _, span := range w.windows {
+		window, err := spidy.ParseWindow(span)
+		if err != nil {
+			return nil, err
+		}
+
+		windows = append(windows, window)
+	}
+
+	watch := spidy.Watch{
+		Schedule: schedule,
+		Events:   events,
+		Windows:  windows,
+		Dir:      w.dir,
+		Keep:     w.keep,
+	}
+
+	return &watch, nil
+}
+
+//==============================================================================
+
+// runWatch re-runs a crawl on a schedule as a long-lived process, writing the
+// changes of each run whose findings differ from the run before it.
+func runWatch(args []string) {
+	var opts options
+	var wopts watchOptions
+
+	fs := flag.NewFlagSet("spidy watch", flag.ExitOnError)
+	opts.register(fs)
+	wopts.register(fs)
+
+	fs.Usage = func() {
+		fmt.Print(`
+Spidy Watch - Re-runs a crawl on a schedule.
+
+Runs are compared with the run before them, and only newly broken or fixed
+findings are written to the output and posted to the notify URL. The reports
+of the last runs are kept in the runs directory, the latest of which the first
+run is compared with.
+
+Flags:
+
+ The same flags as spidy apply, with -format and -output setting how the
+ changes of a run are written.
+
+ -every "Interval to crawl on, e.g 6h"
+ -cron "Cron expression to crawl on, e.g \"0 3 * * *\""
+ -window "Span of the day crawls are confined to, e.g 22:00-06:00, can be repeated"
+ -keep "Number of runs to keep the reports of, defaults to 10"
+ -runs-dir "Directory the reports of the runs are kept in, defaults to spidy-runs"
+ -notify "URL to post the changes of a run to as JSON"
+
+Usage:
+
+	// To crawl a site every 6 hours
+	spidy watch -url http://example.com -every 6h
+
+	// To crawl a site hourly during the night, posting changes to a webhook
+	spidy watch -url http://example.com -cron @hourly -window 22:00-06:00 -notify https://hooks.example.com/spidy
+
+`)
+	}
+
+	fs.Parse(args)
+	opts.env()
+	wopts.env()
+
+	if opts.target == "" && opts.base == "" && opts.manifest == "" {
+		events.ErrorEvent(context, "watch", errors.New("No URL, base or manifest given"), "Configuration Error : Initialization Failed")
+		os.Exit(1)
+	}
+
+	watch, err := wopts.watch()
+	if err != nil {
+		events.ErrorEvent(context, "watch", err, "Configuration Error : Initialization Failed")
+		os.Exit(1)
+	}
+
+	watch.Notify = func(context interface{}, diff *spidy.DiffReport) error {
+		if err := opts.write(func(w io.Writer) error {
+			return spidy.WriteDiff(w, diff, opts.format)
+		}); err != nil {
+			return err
+		}
+
+		return notifyWebhook(wopts.notify, diff)
+	}
+
+	// Each run builds its config afresh, so stores and suppressions are
+	// never carried over from a previous run.
+	crawl := func() (*spidy.Report, error) {
+		conf, err := opts.config()
+		if err != nil {
+			opts.close()
+			return nil, err
+		}
+
+		report, err := spidy.Crawl(context, &conf)
+
+		if err := opts.close(); err != nil {
+			events.ErrorEvent(context, "watch", err, "Closing Stores")
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := opts.writeGraph(report); err != nil {
+			events.ErrorEvent(context, "watch", err, "Writing Graph")
+		}
+
+		return report, nil
+	}
+
+	// The watch stops once the run in progress, if any, is done.
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		close(stop)
+	}()
+
+	if err := watch.Run(context, crawl, stop); err != nil {
+		events.ErrorEvent(context, "watch", err, "Completed")
+		os.Exit(1)
+	}
+}
+
+// notifyWebhook posts the giving diff as JSON to the giving URL, if any.
+func notifyWebhook(url string, diff *spidy.DiffReport) error {
+	if url == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: 30 * time.Second}
+
+	res, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Notify failed : Status[%d]", res.StatusCode)
+	}
+
+	return nil
+}